@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
 	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
 	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
 
 	_ "github.com/lib/pq"
 )
@@ -18,6 +22,13 @@ import (
 var activationBlock = getActivationBlock()
 var fastSyncBlockHeight = getFastSyncBlockHeight()
 
+// every block is stored holding it, so the backfill refuses to run next to a sync leader
+var leaderLockKey = leader.LockKey()
+
+const blockStoreTimeout = 120 * time.Second
+
+var logger = slog.Default()
+
 func getActivationBlock() int32 {
 	if height := os.Getenv("ACTIVATION_BLOCK_HEIGHT"); height != "" {
 		if h, err := strconv.ParseInt(height, 10, 32); err == nil {
@@ -37,46 +48,69 @@ func getFastSyncBlockHeight() int32 {
 }
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	os.Exit(run())
+}
+
+func run() int {
+	logger = logging.Setup("backfill")
+
+	ctx, stop := shutdown.Context()
+	defer stop()
+
 	bitcoinClient := node.NewClient(os.Getenv("BITCOIN_NODE_URI"), os.Getenv("BITCOIN_NODE_USER"), os.Getenv("BITCOIN_NODE_PASSWORD"))
 	spacesClient := node.NewClient(os.Getenv("SPACES_NODE_URI"), "test", "test")
 
 	bc := node.BitcoinClient{Client: bitcoinClient}
 	sc := node.SpacesClient{Client: spacesClient}
 
-	pg, err := pgx.Connect(context.Background(), os.Getenv("POSTGRES_URI"))
+	shutdownTracing, err := tracing.Setup(ctx, "backfill")
 	if err != nil {
-		log.Fatalln(err)
+		logger.Error("failed to set up tracing", "error", err)
+		return shutdown.ExitError
 	}
-	defer pg.Close(context.Background())
+	defer shutdownTracing(context.Background())
+
+	pg, err := store.NewPool(ctx, os.Getenv("POSTGRES_URI"), 0)
+	if err != nil {
+		logger.Error("invalid POSTGRES_URI", "error", err)
+		return shutdown.ExitError
+	}
+	defer pg.Close()
 
 	updateInterval, err := strconv.Atoi(os.Getenv("UPDATE_DB_INTERVAL"))
 	if err != nil {
-		log.Fatalln(err)
+		logger.Error("invalid UPDATE_DB_INTERVAL", "error", err)
+		return shutdown.ExitError
 	}
 
 	for {
-
-		if err := syncGapBlocks(pg, &bc, &sc); err != nil {
-			log.Println(err)
-			time.Sleep(time.Duration(updateInterval) * time.Second)
-			continue
+		err := syncGapBlocks(ctx, pg, &bc, &sc)
+		if err == nil {
+			logger.Info("gap has been filled")
+			return shutdown.ExitOK
+		}
+		if ctx.Err() != nil {
+			logger.Info("gap sync interrupted", "error", err)
+			return shutdown.ExitInterrupted
+		}
+		logger.Error("gap sync failed", "error", err)
+		if !shutdown.Sleep(ctx, time.Duration(updateInterval)*time.Second) {
+			return shutdown.ExitInterrupted
 		}
-		log.Print("gap has been filled")
-		return
 	}
 }
 
-func syncGapBlocks(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.SpacesClient) error {
-	ctx := context.Background()
+// syncGapBlocks finds the highest range of missing blocks below the synced head and stores it,
+// a shutdown signal stops it between blocks
+func syncGapBlocks(ctx context.Context, pg store.DB, bc *node.BitcoinClient, sc *node.SpacesClient) error {
 	var gapStarted bool = false
 	var height, gapBeginning int32
 
-	maxHeight, hash, err := store.GetSyncedHead(pg, bc)
+	maxHeight, hash, err := store.GetSyncedHead(ctx, pg, bc)
 	if err != nil {
 		return err
 	}
-	log.Printf("found synced block of height %d and hash %s", maxHeight, hash)
+	logger.Info("found synced block", "height", maxHeight, "block_hash", hash)
 
 	q := db.New(pg)
 	for height = maxHeight; ; height-- {
@@ -88,7 +122,7 @@ func syncGapBlocks(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.SpacesClient)
 		block, err := q.GetBlockByHeight(ctx, height)
 		if err == nil {
 			if !gapStarted {
-				log.Printf("going down, got block at height %d with hash %s", height, block.Hash)
+				logger.Debug("going down", "height", height, "block_hash", block.Hash)
 				continue
 			}
 			break
@@ -96,56 +130,48 @@ func syncGapBlocks(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.SpacesClient)
 		if err == pgx.ErrNoRows {
 			if !gapStarted {
 				gapBeginning = height
-				log.Print("gap began at ", gapBeginning)
+				logger.Info("gap began", "height", gapBeginning)
 			}
 			gapStarted = true
-			log.Printf("found gap at height %d", height)
+			logger.Debug("found gap", "height", height)
 			continue
 		}
 		return err
 	}
 
 	if !gapStarted {
-		log.Printf("no gaps found")
+		logger.Info("no gaps found")
 		return nil
 	}
 
-	log.Printf("the gap end is at the height of %d", height)
-	nextBlockHash, err := bc.GetBlockHash(context.Background(), int(height+1))
+	logger.Info("found gap end", "height", height)
+	nextBlockHash, err := bc.GetBlockHash(ctx, int(height+1))
 	if err != nil {
 		return err
 	}
 
-	for nextBlockHash != nil {
+	for height := height + 1; height <= gapBeginning; height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		block, err := bc.GetBlock(ctx, nextBlockHash.String())
 		if err != nil {
 			return err
 		}
 
-		if block.Height <= gapBeginning {
-			if err := store.StoreBlock(ctx, pg, block, sc, activationBlock); err != nil {
-				return err
-			}
-		} else {
-			sqlTx, err := pg.Begin(ctx)
-			defer sqlTx.Rollback(ctx)
-			if err != nil {
-				return err
-			}
-
-			log.Printf("updating spenders from the block %d", block.Height)
-			q := db.New(sqlTx)
-			for _, transaction := range block.Transactions {
-				_, err := store.UpdateTxSpenders(q, &transaction, block.Hash)
-				if err != nil {
-					return err
-				}
-			}
-			sqlTx.Commit(ctx)
+		// the block commits even when a shutdown is requested while it is being stored
+		storeCtx, cancel := shutdown.Detach(ctx, blockStoreTimeout)
+		// every block records its progress towards the gap beginning in its own db transaction
+		err = store.StoreRangeBlock(storeCtx, pg, block, sc, activationBlock, store.ComponentBackfill, gapBeginning, leader.XactFence(leaderLockKey))
+		cancel()
+		if err != nil {
+			return err
 		}
 
+		if block.NextBlockHash == nil {
+			break
+		}
 		nextBlockHash = &block.NextBlockHash
-
 	}
 	return nil
 
@@ -2,21 +2,30 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	_ "github.com/lib/pq"
 	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
 	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
 	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	"github.com/spacesprotocol/explorer-indexer/pkg/types"
 )
 
 var activationBlock = getActivationBlock()
 var syncEndHeight = getSyncEndHeight()
 
+// every commit takes it, so the populate refuses to run next to a sync leader
+var leaderLockKey = leader.LockKey()
+
+var logger = slog.Default()
+
 func getActivationBlock() int32 {
 	if height := os.Getenv("ACTIVATION_BLOCK_HEIGHT"); height != "" {
 		if h, err := strconv.ParseInt(height, 10, 32); err == nil {
@@ -36,24 +45,40 @@ func getSyncEndHeight() int32 {
 }
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	os.Exit(run())
+}
+
+func run() int {
+	logger = logging.Setup("populate")
 
 	// Check if activation block is set
 	if activationBlock <= 0 {
-		log.Fatalln("ACTIVATION_BLOCK_HEIGHT environment variable must be set and greater than 0")
+		logger.Error("ACTIVATION_BLOCK_HEIGHT environment variable must be set and greater than 0")
+		return shutdown.ExitError
 	}
 
+	ctx, stop := shutdown.Context()
+	defer stop()
+
 	// Initialize clients
 	bitcoinClient := node.NewClient(os.Getenv("BITCOIN_NODE_URI"), os.Getenv("BITCOIN_NODE_USER"), os.Getenv("BITCOIN_NODE_PASSWORD"))
 	spacesClient := node.NewClient(os.Getenv("SPACES_NODE_URI"), "test", "test")
 	bc := node.BitcoinClient{Client: bitcoinClient}
 	sc := node.SpacesClient{Client: spacesClient}
 
-	pg, err := pgx.Connect(context.Background(), os.Getenv("POSTGRES_URI"))
+	shutdownTracing, err := tracing.Setup(ctx, "populate")
 	if err != nil {
-		log.Fatalln(err)
+		logger.Error("failed to set up tracing", "error", err)
+		return shutdown.ExitError
 	}
-	defer pg.Close(context.Background())
+	defer shutdownTracing(context.Background())
+
+	pg, err := store.NewPool(ctx, os.Getenv("POSTGRES_URI"), 0)
+	if err != nil {
+		logger.Error("invalid POSTGRES_URI", "error", err)
+		return shutdown.ExitError
+	}
+	defer pg.Close()
 
 	// Get retry settings
 	updateInterval, err := strconv.Atoi(os.Getenv("UPDATE_DB_INTERVAL"))
@@ -62,18 +87,27 @@ func main() {
 	}
 
 	for {
-		if err := syncSpacesTransactions(pg, &bc, &sc); err != nil {
-			log.Printf("Sync failed: %v. Retrying in %d seconds...", err, updateInterval)
-			time.Sleep(time.Duration(updateInterval) * time.Second)
-			continue
+		err := syncSpacesTransactions(ctx, pg, &bc, &sc)
+		if err == nil {
+			logger.Info("spaces transactions sync completed")
+			return shutdown.ExitOK
+		}
+		if ctx.Err() != nil {
+			logger.Info("spaces transactions sync interrupted", "error", err)
+			return shutdown.ExitInterrupted
+		}
+		logger.Error("sync failed, retrying", "error", err, "retry_in", time.Duration(updateInterval)*time.Second)
+		if !shutdown.Sleep(ctx, time.Duration(updateInterval)*time.Second) {
+			return shutdown.ExitInterrupted
 		}
-		log.Print("Spaces transactions sync completed successfully")
-		return
 	}
 }
 
-func syncSpacesTransactions(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.SpacesClient) error {
-	ctx := context.Background()
+// syncSpacesTransactions stores the spaces transactions block by block,
+// on shutdown it commits the blocks processed so far and stops
+func syncSpacesTransactions(shutdownCtx context.Context, pg store.DB, bc *node.BitcoinClient, sc *node.SpacesClient) error {
+	// a block that started processing is finished even when a shutdown is requested
+	ctx := context.WithoutCancel(shutdownCtx)
 
 	// Create a transaction for tracking sync progress
 	tx, err := pg.Begin(ctx)
@@ -86,23 +120,66 @@ func syncSpacesTransactions(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.Space
 	// Get current chain height if end height is not specified
 	endHeight := syncEndHeight
 	if endHeight == -1 {
-		blockCount, err := q.GetBlocksMaxHeight(ctx)
+		blocksState, err := store.GetState(ctx, q, store.ComponentBlocks)
 		if err != nil {
 			return err
 		}
-		endHeight = int32(blockCount)
-		log.Printf("Using current chain height: %d", endHeight)
+		if blocksState != nil {
+			endHeight = blocksState.Height
+		} else {
+			blockCount, err := q.GetBlocksMaxHeight(ctx)
+			if err != nil {
+				return err
+			}
+			endHeight = int32(blockCount)
+		}
+		logger.Info("using current chain height", "height", endHeight)
+	}
+
+	// resume after the last block a previous run committed if it did not reach its target,
+	// a finished run is populated again from the activation block
+	startHeight := activationBlock
+	state, err := store.GetState(ctx, q, store.ComponentPopulate)
+	if err != nil {
+		return err
+	}
+	if state != nil && state.TargetHeight.Valid && state.Height < state.TargetHeight.Int32 && state.Height >= activationBlock {
+		startHeight = state.Height + 1
+		logger.Info("resuming unfinished run", "height", state.Height, "target_height", state.TargetHeight.Int32)
+	}
+
+	logger.Info("starting spaces transactions sync", "from_height", startHeight, "to_height", endHeight)
+
+	var lastHash *types.Bytes
+	lastHeight := startHeight - 1
+	commit := func() error {
+		if err := leader.LockXact(ctx, db.New(tx), leaderLockKey); err != nil {
+			return err
+		}
+		if lastHash != nil {
+			if err := store.UpdateRangeState(ctx, db.New(tx), store.ComponentPopulate, lastHeight, lastHash, endHeight); err != nil {
+				return err
+			}
+		}
+		return tx.Commit(ctx)
 	}
 
-	log.Printf("Starting spaces transactions sync from block %d to %d", activationBlock, endHeight)
+	for height := startHeight; height <= endHeight; height++ {
+		if err := shutdownCtx.Err(); err != nil {
+			if err := commit(); err != nil {
+				return err
+			}
+			logger.Info("committed progress before shutdown", "height", height-1)
+			return err
+		}
 
-	for height := activationBlock; height <= endHeight; height++ {
 		blockHash, err := bc.GetBlockHash(ctx, int(height))
 		if err != nil {
 			return err
 		}
 
-		log.Printf("Processing block %d with hash %s", height, blockHash.String())
+		blockLogger := logger.With("height", height, "block_hash", blockHash)
+		blockLogger.Info("processing block")
 		spacesBlock, err := sc.GetBlockMeta(ctx, blockHash.String())
 		if err != nil {
 			return err
@@ -111,25 +188,19 @@ func syncSpacesTransactions(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.Space
 		start := time.Now()
 		txCount := len(spacesBlock.Transactions)
 
-		for txIndex, spaceTx := range spacesBlock.Transactions {
-			if txIndex > 0 && txIndex%50 == 0 {
-				log.Printf("  Processed %d/%d transactions in block %d", txIndex, txCount, height)
-			}
-
-			tx, err = store.StoreSpacesTransaction(spaceTx, *blockHash, tx)
-			if err != nil {
-				return err
-			}
+		tx, err = store.StoreSpacesTransactions(ctx, spacesBlock.Transactions, *blockHash, tx)
+		if err != nil {
+			return err
 		}
 
 		// Log completion for this block
 		elapsed := time.Since(start)
-		log.Printf("Block %d completed in %s with %d spaces transactions",
-			height, elapsed, txCount)
+		blockLogger.Info("block completed", "duration", elapsed, "spaces_txs", txCount)
+		lastHeight, lastHash = height, blockHash
 
 		// Commit every N blocks to avoid large transactions
 		if height%10 == 0 {
-			if err := tx.Commit(ctx); err != nil {
+			if err := commit(); err != nil {
 				return err
 			}
 
@@ -140,15 +211,15 @@ func syncSpacesTransactions(pg *pgx.Conn, bc *node.BitcoinClient, sc *node.Space
 			}
 			q = db.New(tx)
 
-			log.Printf("Committed progress at block %d", height)
+			blockLogger.Info("committed progress")
 		}
 	}
 
 	// Final commit
-	if err := tx.Commit(ctx); err != nil {
+	if err := commit(); err != nil {
 		return err
 	}
 
-	log.Printf("Successfully synced spaces transactions from block %d to %d", activationBlock, endHeight)
+	logger.Info("synced spaces transactions", "from_height", startHeight, "to_height", endHeight)
 	return nil
 }
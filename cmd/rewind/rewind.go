@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+)
+
+// the rewind refuses to run while a sync leader holds it
+var leaderLockKey = leader.LockKey()
+
+var logger = slog.Default()
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	logger = logging.Setup("rewind")
+
+	toHeight := flag.Int("to-height", -2, "last block height to keep, -1 removes every block")
+	yes := flag.Bool("yes", false, "skip the confirmation prompt")
+	orphan := flag.Bool("orphan", false, "mark the blocks above the height as orphans instead of deleting them")
+	flag.Parse()
+
+	if *toHeight < -1 {
+		fmt.Fprintln(os.Stderr, "usage: rewind --to-height H [--orphan] [--yes]")
+		flag.PrintDefaults()
+		return shutdown.ExitError
+	}
+	height := int32(*toHeight)
+
+	ctx, stop := shutdown.Context()
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, "rewind")
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		return shutdown.ExitError
+	}
+	defer shutdownTracing(context.Background())
+
+	pg, err := store.NewPool(ctx, os.Getenv("POSTGRES_URI"), 0)
+	if err != nil {
+		logger.Error("invalid POSTGRES_URI", "error", err)
+		return shutdown.ExitError
+	}
+	defer pg.Close()
+
+	if err := rewind(ctx, pg, height, *orphan, *yes); err != nil {
+		if ctx.Err() != nil {
+			logger.Info("rewind interrupted, nothing was changed", "error", err)
+			return shutdown.ExitInterrupted
+		}
+		logger.Error("rewind failed", "error", err)
+		return shutdown.ExitError
+	}
+	return shutdown.ExitOK
+}
+
+// rewind prints what removing everything above height changes and, once confirmed,
+// removes it in one db transaction. Nothing is locked while the prompt waits.
+func rewind(ctx context.Context, pg store.DB, height int32, orphan bool, yes bool) error {
+	q := db.New(pg)
+	if holder, err := q.GetAdvisoryLockHolder(ctx, leaderLockKey); err == nil {
+		return fmt.Errorf("sync leader %q is running, stop it before rewinding", holder.String)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	counts, syncedHeight, err := summary(ctx, q, height)
+	if err != nil {
+		return err
+	}
+	action := "delete"
+	if orphan {
+		action = "orphan"
+	}
+	fmt.Printf("rewinding to height %d will %s %d blocks with %d transactions and %d spaces actions\n",
+		height, action, counts.Blocks, counts.Transactions, counts.Vmetaouts)
+
+	states, err := q.GetIndexerStates(ctx)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		if state.Height > height {
+			fmt.Printf("  %s checkpoint moves from %d to %d\n", state.Component, state.Height, height)
+		}
+	}
+
+	if !yes && !confirm() {
+		return fmt.Errorf("not confirmed")
+	}
+
+	ctx, tx, err := tracing.BeginTx(ctx, pg, "rewind")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q = db.New(tx)
+	// held until the transaction ends, keeps a sync leader from starting meanwhile
+	if err := leader.LockXact(ctx, q, leaderLockKey); err != nil {
+		return err
+	}
+	// the index may have moved while the prompt waited
+	lockedCounts, lockedSyncedHeight, err := summary(ctx, q, height)
+	if err != nil {
+		return err
+	}
+	if lockedCounts != counts || lockedSyncedHeight != syncedHeight {
+		return fmt.Errorf("the index changed since the summary was printed, run the rewind again")
+	}
+
+	if err := store.RewindToHeight(ctx, q, height, orphan); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.Info("rewound index", "height", height, "orphan", orphan, "blocks", counts.Blocks)
+	return nil
+}
+
+// summary counts the rows above height and reads the synced height
+func summary(ctx context.Context, q *db.Queries, height int32) (db.CountRowsAfterHeightRow, int32, error) {
+	counts, err := q.CountRowsAfterHeight(ctx, height)
+	if err != nil {
+		return counts, -1, err
+	}
+	syncedHeight, err := store.SyncedHeight(ctx, q)
+	return counts, syncedHeight, err
+}
+
+func confirm() bool {
+	fmt.Print("continue? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
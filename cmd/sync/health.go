@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+)
+
+const healthDBTimeout = 5 * time.Second
+
+// tracks the progress of the sync workers for the health endpoints
+type healthState struct {
+	mu              sync.Mutex
+	leaderSince     time.Time
+	workers         map[string]*workerHealth
+	syncedHeight    int32
+	nodeTipHeight   int32
+	spacedTipHeight int32
+}
+
+type workerHealth struct {
+	lastPassAt time.Time
+	// set by the passes that report progress while running, and at the end of every successful pass
+	lastProgressAt time.Time
+	lastError      string
+}
+
+type workerStatus struct {
+	LastPassAt     *time.Time `json:"last_pass_at,omitempty"`
+	LastProgressAt *time.Time `json:"last_progress_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+type healthCheck struct {
+	Ok     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type healthStatus struct {
+	Status          string                  `json:"status"`
+	InstanceID      string                  `json:"instance_id"`
+	IsLeader        bool                    `json:"is_leader"`
+	Leader          string                  `json:"leader"`
+	Workers         map[string]workerStatus `json:"workers"`
+	SyncedHeight    int32                   `json:"synced_height"`
+	NodeTipHeight   int32                   `json:"node_tip_height"`
+	SpacedTipHeight int32                   `json:"spaced_tip_height"`
+	Checks          map[string]healthCheck  `json:"checks"`
+}
+
+var health = &healthState{
+	workers:         make(map[string]*workerHealth),
+	syncedHeight:    -1,
+	nodeTipHeight:   -1,
+	spacedTipHeight: -1,
+}
+
+var healthMaxPassAge = getHealthMaxPassAge()
+var healthMaxTipLag = getHealthMaxTipLag()
+
+func getHealthMaxPassAge() time.Duration {
+	if seconds := os.Getenv("HEALTH_MAX_PASS_AGE_SECONDS"); seconds != "" {
+		if s, err := strconv.ParseInt(seconds, 10, 32); err == nil {
+			return time.Duration(s) * time.Second
+		}
+	}
+	return 2 * syncTimeout
+}
+
+func getHealthMaxTipLag() int32 {
+	if lag := os.Getenv("HEALTH_MAX_TIP_LAG"); lag != "" {
+		if l, err := strconv.ParseInt(lag, 10, 32); err == nil {
+			return int32(l)
+		}
+	}
+	return 6
+}
+
+func (h *healthState) setTips(nodeTip, spacedTip int32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodeTipHeight = nodeTip
+	h.spacedTipHeight = spacedTip
+}
+
+func (h *healthState) setSyncedHeight(height int32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.syncedHeight = height
+}
+
+// leading resets the worker checks when this instance becomes the leader
+func (h *healthState) leading() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaderSince = time.Now()
+}
+
+// register adds a worker to the health checks before its first pass
+func (h *healthState) register(worker string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.workers[worker] = &workerHealth{}
+}
+
+// progress records that a running pass of the worker moved forward, e.g. stored a block
+func (h *healthState) progress(worker string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if w, ok := h.workers[worker]; ok {
+		w.lastProgressAt = time.Now()
+	}
+}
+
+// passFinished records the end of a worker pass, err is nil if the pass succeeded
+func (h *healthState) passFinished(worker string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.workers[worker]
+	if !ok {
+		w = &workerHealth{}
+		h.workers[worker] = w
+	}
+	if err != nil {
+		w.lastError = err.Error()
+		return
+	}
+	w.lastError = ""
+	w.lastPassAt = time.Now()
+	w.lastProgressAt = w.lastPassAt
+}
+
+// status runs the health checks. Liveness only checks that the workers of the leader make progress,
+// so that a database outage or a node far ahead does not restart the instance;
+// readiness also checks the database and the tip lag. A standby's workers are idle until it leads.
+func (h *healthState) status(ctx context.Context, pg *pgxpool.Pool, elector *leader.Elector, ready bool) healthStatus {
+	isLeader := elector.IsLeader()
+	h.mu.Lock()
+	status := healthStatus{
+		InstanceID:      elector.ID(),
+		IsLeader:        isLeader,
+		Workers:         make(map[string]workerStatus, len(h.workers)),
+		SyncedHeight:    h.syncedHeight,
+		NodeTipHeight:   h.nodeTipHeight,
+		SpacedTipHeight: h.spacedTipHeight,
+		Checks:          make(map[string]healthCheck),
+	}
+	for name, w := range h.workers {
+		workerStatus := workerStatus{LastError: w.lastError}
+		if !w.lastPassAt.IsZero() {
+			lastPassAt := w.lastPassAt
+			workerStatus.LastPassAt = &lastPassAt
+		}
+		if !w.lastProgressAt.IsZero() {
+			lastProgressAt := w.lastProgressAt
+			workerStatus.LastProgressAt = &lastProgressAt
+		}
+		status.Workers[name] = workerStatus
+		if !isLeader {
+			continue
+		}
+		if w.lastProgressAt.After(h.leaderSince) {
+			status.Checks[name+"_pass"] = passCheck(time.Since(w.lastProgressAt))
+		} else {
+			status.Checks[name+"_pass"] = passCheck(time.Since(h.leaderSince))
+		}
+	}
+	h.mu.Unlock()
+
+	if currentLeader, err := elector.Leader(ctx); err == nil {
+		status.Leader = currentLeader
+	}
+
+	if ready {
+		status.Checks["database"] = databaseCheck(ctx, pg)
+	}
+	if ready && isLeader {
+		status.Checks["node_tip_lag"] = lagCheck(status.SyncedHeight, status.NodeTipHeight)
+		status.Checks["spaced_tip_lag"] = lagCheck(status.SyncedHeight, status.SpacedTipHeight)
+	}
+
+	status.Status = "ok"
+	for _, check := range status.Checks {
+		if !check.Ok {
+			status.Status = "unhealthy"
+		}
+	}
+	return status
+}
+
+func passCheck(age time.Duration) healthCheck {
+	if age > healthMaxPassAge {
+		return healthCheck{Ok: false, Detail: "no sync progress in " + age.Round(time.Second).String()}
+	}
+	return healthCheck{Ok: true}
+}
+
+func databaseCheck(ctx context.Context, pg *pgxpool.Pool) healthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthDBTimeout)
+	defer cancel()
+	if err := pg.Ping(ctx); err != nil {
+		return healthCheck{Ok: false, Detail: err.Error()}
+	}
+	return healthCheck{Ok: true}
+}
+
+func lagCheck(syncedHeight, tipHeight int32) healthCheck {
+	if tipHeight < 0 {
+		return healthCheck{Ok: false, Detail: "tip unknown"}
+	}
+	if lag := tipHeight - syncedHeight; lag > healthMaxTipLag {
+		return healthCheck{Ok: false, Detail: fmt.Sprintf("%d blocks behind tip %d", lag, tipHeight)}
+	}
+	return healthCheck{Ok: true}
+}
+
+func healthHandler(pg *pgxpool.Pool, elector *leader.Elector, ready bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := health.status(r.Context(), pg, elector, ready)
+		w.Header().Set("Content-Type", "application/json")
+		if status.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
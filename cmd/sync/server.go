@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+)
+
+// serves the metrics and health endpoints in the background
+func startHTTPServer(addr string, pg *pgxpool.Pool, elector *leader.Elector) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/healthz", healthHandler(pg, elector, false))
+	mux.Handle("/readyz", healthHandler(pg, elector, true))
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		logger.Info("serving metrics and health checks", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("http server stopped", "error", err)
+		}
+	}()
+	return server
+}
+
+func stopHTTPServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Warn("error stopping http server", "error", err)
+	}
+}
@@ -2,18 +2,25 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"strings"
 
-	"log"
 	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/jackc/pgx/v5"
-
 	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/mempool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
 	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
 	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
 
 	_ "github.com/lib/pq"
 	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
@@ -22,10 +29,28 @@ import (
 var activationBlock = getActivationBlock()
 var fastSyncBlockHeight = getFastSyncBlockHeight()
 var mempoolChunkSize = getMempoolChunkSize()
+var feeSnapshotRetention = getFeeSnapshotRetention()
+var feeSnapshotInterval = getSeconds("FEE_SNAPSHOT_INTERVAL", time.Minute)
+var httpAddr = getHTTPAddr()
+var poolSize = getPoolSize()
+var instanceID = getInstanceID()
+var leaderLockKey = leader.LockKey()
+var leaderElectionInterval = getSeconds("LEADER_ELECTION_INTERVAL", 5*time.Second)
+
+var logger = slog.Default()
+
+// confirmation targets stored with every fee snapshot
+var feeEstimateTargets = []int{1, 2, 3, 6, 12, 24, 144}
 
-const deadbeefString = "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
 const mempoolSyncTimeout = 30 * time.Second //in seconds
 const syncTimeout = 300 * time.Second
+const blockStoreTimeout = 120 * time.Second
+
+// timeout of the rollouts and root anchors workers
+const auxSyncTimeout = 60 * time.Second
+
+// held while a block is stored, the mempool syncer holds it around its db transactions
+var chainMu sync.Mutex
 
 func getMempoolChunkSize() int {
 	if height := os.Getenv("MEMPOOL_CHUNK_SIZE"); height != "" {
@@ -36,6 +61,38 @@ func getMempoolChunkSize() int {
 	return 200
 }
 
+func getPoolSize() int32 {
+	if size := os.Getenv("POSTGRES_POOL_SIZE"); size != "" {
+		if s, err := strconv.ParseInt(size, 10, 32); err == nil {
+			return int32(s)
+		}
+	}
+	return 10
+}
+
+func getInstanceID() string {
+	if id := os.Getenv("INSTANCE_ID"); id != "" {
+		return id
+	}
+	return leader.DefaultID()
+}
+
+func getHTTPAddr() string {
+	if addr := os.Getenv("HTTP_ADDR"); addr != "" {
+		return addr
+	}
+	return ":8080"
+}
+
+func getFeeSnapshotRetention() time.Duration {
+	if hours := os.Getenv("FEE_SNAPSHOT_RETENTION_HOURS"); hours != "" {
+		if h, err := strconv.ParseInt(hours, 10, 32); err == nil {
+			return time.Duration(h) * time.Hour
+		}
+	}
+	return 7 * 24 * time.Hour
+}
+
 func getActivationBlock() int32 {
 	if height := os.Getenv("ACTIVATION_BLOCK_HEIGHT"); height != "" {
 		if h, err := strconv.ParseInt(height, 10, 32); err == nil {
@@ -55,7 +112,22 @@ func getFastSyncBlockHeight() int32 {
 }
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	os.Exit(run())
+}
+
+func run() int {
+	logger = logging.Setup("sync")
+
+	ctx, stop := shutdown.Context()
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, "sync")
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		return shutdown.ExitError
+	}
+	defer shutdownTracing(context.Background())
+
 	bitcoinClient := node.NewClient(os.Getenv("BITCOIN_NODE_URI"), os.Getenv("BITCOIN_NODE_USER"), os.Getenv("BITCOIN_NODE_PASSWORD"))
 	spacesClient := node.NewClient(os.Getenv("SPACES_NODE_URI"), os.Getenv("RPC_USER"), os.Getenv("RPC_PASSWORD"))
 
@@ -64,49 +136,71 @@ func main() {
 
 	updateInterval, err := strconv.Atoi(os.Getenv("UPDATE_DB_INTERVAL"))
 	if err != nil {
-		log.Fatalln(err)
+		logger.Error("invalid UPDATE_DB_INTERVAL", "error", err)
+		return shutdown.ExitError
 	}
 
-	for {
-		connCtx, connCancel := context.WithTimeout(context.Background(), 30*time.Second)
-		pg, err := pgx.Connect(connCtx, os.Getenv("POSTGRES_URI"))
-		connCancel()
-
-		if err != nil {
-			log.Printf("failed to connect to database: %v", err)
-			time.Sleep(time.Second)
-			continue
-		}
-
-		syncCtx, syncCancel := context.WithTimeout(context.Background(), syncTimeout)
-
-		if err := syncBlocks(syncCtx, pg, &bc, &sc); err != nil {
-			log.Println(err)
-			syncCancel()
-			pg.Close(context.Background())
-			time.Sleep(time.Second)
-			continue
-		}
+	pg, err := store.NewPool(ctx, os.Getenv("POSTGRES_URI"), poolSize)
+	if err != nil {
+		logger.Error("invalid POSTGRES_URI", "error", err)
+		return shutdown.ExitError
+	}
+	defer pg.Close()
+
+	elector := leader.New(pg, leaderLockKey, instanceID, leaderElectionInterval)
+
+	server := startHTTPServer(httpAddr, pg, elector)
+	defer stopHTTPServer(server)
+
+	mempoolSyncer := mempool.New(pg, &bc, &sc, &chainMu, feeEstimateTargets, feeSnapshotRetention, feeSnapshotInterval)
+
+	interval := time.Duration(updateInterval) * time.Second
+	workers := []worker{
+		newWorker("blocks", interval, time.Second, syncTimeout, func(ctx context.Context) error {
+			return syncBlocks(ctx, pg, &bc, &sc, elector.Fence)
+		}),
+		newWorker("mempool", interval, interval, mempoolSyncTimeout, func(ctx context.Context) error {
+			return mempoolSyncer.Sync(ctx)
+		}),
+		newWorker("rollouts", interval, interval, auxSyncTimeout, func(ctx context.Context) error {
+			return syncRollouts(ctx, pg, &sc)
+		}),
+		newWorker("root_anchors", interval, interval, auxSyncTimeout, func(ctx context.Context) error {
+			return syncRootAnchors(ctx, pg, &sc)
+		}),
+	}
 
-		if err := syncMempool(syncCtx, pg, &bc, &sc); err != nil {
-			log.Println(err)
-			syncCancel()
-			pg.Close(context.Background())
-			time.Sleep(time.Duration(updateInterval) * time.Second)
-			continue
-		}
+	for _, w := range workers {
+		health.register(w.name)
+	}
 
-		syncCancel()
-		if err := pg.Close(context.Background()); err != nil {
-			log.Printf("error closing connection: %v", err)
+	// only the leader writes, a standby waits here until the leader goes away
+	var failed atomic.Bool
+	elector.Run(ctx, func(ctx context.Context) {
+		health.leading()
+		var wg sync.WaitGroup
+		for _, w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !supervise(ctx, pg, w) {
+					failed.Store(true)
+				}
+			}()
 		}
+		wg.Wait()
+	})
 
-		time.Sleep(time.Duration(updateInterval) * time.Second)
+	exitCode := shutdown.ExitOK
+	if failed.Load() {
+		exitCode = shutdown.ExitError
 	}
+	logger.Info("shut down", "exit_code", exitCode)
+	return exitCode
 }
 
-func syncRootAnchors(ctx context.Context, pg *pgx.Conn, sc *node.SpacesClient) error {
-	sqlTx, err := pg.BeginTx(ctx, pgx.TxOptions{})
+func syncRootAnchors(ctx context.Context, pg store.DB, sc *node.SpacesClient) error {
+	ctx, sqlTx, err := tracing.BeginTx(ctx, pg, "syncRootAnchors")
 	if err != nil {
 		return err
 	}
@@ -119,12 +213,20 @@ func syncRootAnchors(ctx context.Context, pg *pgx.Conn, sc *node.SpacesClient) e
 	}
 
 	params := db.UpdateRootAnchorParams{}
+	var latest *node.RootAnchor
 	for _, rootAnchor := range result {
 		params.Hash = rootAnchor.Block.Hash
 		params.RootAnchor = &rootAnchor.Root
 
 		if err := q.UpdateRootAnchor(ctx, params); err != nil {
-			log.Printf("error updating root anchor %d: %v", rootAnchor, err)
+			return fmt.Errorf("updating root anchor of block %s: %w", rootAnchor.Block.Hash, err)
+		}
+		if latest == nil || rootAnchor.Block.Height > latest.Block.Height {
+			latest = rootAnchor
+		}
+	}
+	if latest != nil {
+		if err := store.UpdateState(ctx, q, store.ComponentRootAnchors, int32(latest.Block.Height), &latest.Block.Hash); err != nil {
 			return err
 		}
 	}
@@ -135,8 +237,8 @@ func syncRootAnchors(ctx context.Context, pg *pgx.Conn, sc *node.SpacesClient) e
 
 }
 
-func syncRollouts(ctx context.Context, pg *pgx.Conn, sc *node.SpacesClient) error {
-	sqlTx, err := pg.BeginTx(ctx, pgx.TxOptions{})
+func syncRollouts(ctx context.Context, pg store.DB, sc *node.SpacesClient) error {
+	ctx, sqlTx, err := tracing.BeginTx(ctx, pg, "syncRollouts")
 	if err != nil {
 		return err
 	}
@@ -155,49 +257,63 @@ func syncRollouts(ctx context.Context, pg *pgx.Conn, sc *node.SpacesClient) erro
 
 		params := db.InsertRolloutParams{}
 		for _, space := range *result {
-			if space.Name[0] == '@' {
-				params.Name = space.Name[1:]
-			} else {
-				log.Fatalf("found incorrect space name during rollout sync: %s", space.Name)
+			if !strings.HasPrefix(space.Name, "@") {
+				return fmt.Errorf("rollout batch %d: incorrect space name %q", i, space.Name)
 			}
+			params.Name = space.Name[1:]
 			params.Bid = int64(space.Value)
 			params.Target = int64(i)
 			if err := q.InsertRollout(ctx, params); err != nil {
-				log.Printf("error inserting rollout batch %d: %v", i, err)
-				return err
+				return fmt.Errorf("inserting rollout batch %d: %w", i, err)
 			}
 		}
 	}
+	if err := store.CopyState(ctx, q, store.ComponentRollouts); err != nil {
+		return err
+	}
 	if err = sqlTx.Commit(ctx); err != nil {
 		return err
 	}
 	return nil
 }
 
-func syncBlocks(ctx context.Context, pg *pgx.Conn, bc *node.BitcoinClient, sc *node.SpacesClient) error {
+func syncBlocks(ctx context.Context, pg store.DB, bc *node.BitcoinClient, sc *node.SpacesClient, fence store.Fence) (err error) {
+	ctx, span := tracing.Start(ctx, "sync.blocks")
+	defer func() { tracing.End(span, err) }()
+
 	var hash *Bytes
 	height, hash, err := store.GetSyncedHead(ctx, pg, bc)
 	if err != nil {
 		return err
 	}
-	log.Printf("found synced block of height %d and hash %s", height, hash)
+	if hash != nil {
+		if err := store.RewindToSyncedHead(ctx, pg, height, hash, fence); err != nil {
+			return err
+		}
+	}
+	logger.Info("found synced block", "height", height, "block_hash", hash)
 
-	if err := syncRollouts(ctx, pg, sc); err != nil {
-		log.Println(err)
+	nodeTip, err := bc.GetBlockCount(ctx)
+	if err != nil {
 		return err
 	}
+	metrics.NodeTipHeight.Set(float64(nodeTip))
+	metrics.SetSyncedHeight(height, nodeTip)
 
-	if err := syncRootAnchors(ctx, pg, sc); err != nil {
-		log.Println(err)
+	serverInfo, err := sc.GetServerInfo(ctx)
+	if err != nil {
 		return err
 	}
+	metrics.SpacedTipHeight.Set(float64(serverInfo.Tip.Height))
+	health.setTips(nodeTip, int32(serverInfo.Tip.Height))
+	health.setSyncedHeight(height)
 
 	if height < fastSyncBlockHeight {
 		height = fastSyncBlockHeight
 	}
 
 	height++
-	log.Print("trying to get block ", height)
+	logger.Debug("fetching block", "height", height)
 	hash, err = bc.GetBlockHash(ctx, int(height))
 	if err != nil {
 		if strings.Contains(err.Error(), "Block height out of range") {
@@ -211,34 +327,44 @@ func syncBlocks(ctx context.Context, pg *pgx.Conn, bc *node.BitcoinClient, sc *n
 		return err
 	}
 
-	if block.Height >= activationBlock {
-		if err := syncRollouts(ctx, pg, sc); err != nil {
-			log.Println(err)
-			return err
-		}
-
-		if err := syncRootAnchors(ctx, pg, sc); err != nil {
-			log.Println(err)
-			return err
-		}
-	}
-
-	if err := store.StoreBlock(ctx, pg, block, sc, activationBlock); err != nil {
+	if err := storeBlock(ctx, pg, block, sc, fence); err != nil {
 		return err
 	}
+	nodeTip = max(nodeTip, block.Height)
+	metrics.SetSyncedHeight(block.Height, nodeTip)
+	health.setSyncedHeight(block.Height)
+	health.progress("blocks")
 	nextBlockHash := block.NextBlockHash
 
 	for nextBlockHash != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		block, err := bc.GetBlock(ctx, nextBlockHash.String())
 		if err != nil {
 			return err
 		}
 
-		if err := store.StoreBlock(ctx, pg, block, sc, activationBlock); err != nil {
+		if err := storeBlock(ctx, pg, block, sc, fence); err != nil {
 			return err
 		}
+		nodeTip = max(nodeTip, block.Height)
+		metrics.SetSyncedHeight(block.Height, nodeTip)
+		health.setSyncedHeight(block.Height)
+		health.progress("blocks")
 
 		nextBlockHash = block.NextBlockHash
 	}
 	return nil
 }
+
+// storeBlock lets a block that started storing commit even when a shutdown is requested meanwhile,
+// the fence keeps it from committing once the leadership is lost
+func storeBlock(ctx context.Context, pg store.DB, block *node.Block, sc *node.SpacesClient, fence store.Fence) error {
+	chainMu.Lock()
+	defer chainMu.Unlock()
+
+	storeCtx, cancel := shutdown.Detach(ctx, blockStoreTimeout)
+	defer cancel()
+	return store.StoreBlock(storeCtx, pg, block, sc, activationBlock, store.ComponentBlocks, fence)
+}
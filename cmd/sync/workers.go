@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
+)
+
+// a sync job that a supervisor runs periodically in its own goroutine
+type worker struct {
+	name     string
+	interval time.Duration
+	// wait before the next run after a failed one
+	retry   time.Duration
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// newWorker reads the interval and timeout of a worker from <NAME>_SYNC_INTERVAL and
+// <NAME>_SYNC_TIMEOUT in seconds, falling back to the given defaults
+func newWorker(name string, interval, retry, timeout time.Duration, run func(ctx context.Context) error) worker {
+	prefix := strings.ToUpper(name)
+	return worker{
+		name:     name,
+		interval: getSeconds(prefix+"_SYNC_INTERVAL", interval),
+		retry:    retry,
+		timeout:  getSeconds(prefix+"_SYNC_TIMEOUT", timeout),
+		run:      run,
+	}
+}
+
+func getSeconds(key string, fallback time.Duration) time.Duration {
+	if seconds := os.Getenv(key); seconds != "" {
+		if s, err := strconv.ParseInt(seconds, 10, 32); err == nil {
+			return time.Duration(s) * time.Second
+		}
+	}
+	return fallback
+}
+
+// supervise runs the worker until ctx is cancelled, failed and panicking runs are retried.
+// It returns false if a run failed while shutting down for another reason than the shutdown itself.
+func supervise(ctx context.Context, pg *pgxpool.Pool, w worker) bool {
+	logger := logger.With("worker", w.name)
+	logger.Info("worker started", "interval", w.interval, "timeout", w.timeout)
+
+	clean := true
+	for ctx.Err() == nil {
+		err := runWorker(ctx, pg, w)
+		health.passFinished(w.name, err)
+
+		wait := w.interval
+		switch {
+		case err == nil:
+		case ctx.Err() != nil && errors.Is(err, context.Canceled):
+			logger.Info("worker pass interrupted by shutdown", "error", err)
+		case ctx.Err() != nil:
+			// the in-flight block failed to commit while shutting down
+			logger.Error("worker pass failed during shutdown", "error", err)
+			clean = false
+		default:
+			logger.Error("worker pass failed", "error", err)
+			wait = w.retry
+		}
+
+		shutdown.Sleep(ctx, wait)
+	}
+
+	logger.Info("worker stopped")
+	return clean
+}
+
+func runWorker(ctx context.Context, pg *pgxpool.Pool, w worker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	if err := pg.Ping(ctx); err != nil {
+		if ctx.Err() == nil {
+			metrics.DBConnectionFailures.Inc()
+		}
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	return w.run(ctx)
+}
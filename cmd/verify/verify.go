@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+)
+
+// exit code when the db differs from the nodes and the differences were not repaired
+const exitIssues = 2
+
+var activationBlock = getActivationBlock()
+
+// the block repairs refuse to run while a sync leader holds it
+var leaderLockKey = leader.LockKey()
+
+var logger = slog.Default()
+
+func getActivationBlock() int32 {
+	if height := os.Getenv("ACTIVATION_BLOCK_HEIGHT"); height != "" {
+		if h, err := strconv.ParseInt(height, 10, 32); err == nil {
+			return int32(h)
+		}
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	logger = logging.Setup("verify")
+
+	fromHeight := flag.Int("from", 0, "first block height to verify")
+	toHeight := flag.Int("to", -1, "last block height to verify, -1 verifies up to the synced tip")
+	repair := flag.Bool("repair", false, "store again the blocks and root anchors that differ from the nodes")
+	flag.Parse()
+
+	if *fromHeight < 0 || *toHeight < -1 || *toHeight >= 0 && *toHeight < *fromHeight {
+		fmt.Fprintln(os.Stderr, "usage: verify [--from H] [--to H] [--repair]")
+		flag.PrintDefaults()
+		return shutdown.ExitError
+	}
+
+	ctx, stop := shutdown.Context()
+	defer stop()
+
+	bitcoinClient := node.NewClient(os.Getenv("BITCOIN_NODE_URI"), os.Getenv("BITCOIN_NODE_USER"), os.Getenv("BITCOIN_NODE_PASSWORD"))
+	spacesClient := node.NewClient(os.Getenv("SPACES_NODE_URI"), "test", "test")
+
+	bc := node.BitcoinClient{Client: bitcoinClient}
+	sc := node.SpacesClient{Client: spacesClient}
+
+	shutdownTracing, err := tracing.Setup(ctx, "verify")
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		return shutdown.ExitError
+	}
+	defer shutdownTracing(context.Background())
+
+	pg, err := store.NewPool(ctx, os.Getenv("POSTGRES_URI"), 0)
+	if err != nil {
+		logger.Error("invalid POSTGRES_URI", "error", err)
+		return shutdown.ExitError
+	}
+	defer pg.Close()
+
+	report, err := verify(ctx, pg, &bc, &sc, int32(*fromHeight), int32(*toHeight), *repair)
+	if err != nil {
+		if ctx.Err() != nil {
+			logger.Info("verify interrupted", "error", err)
+			return shutdown.ExitInterrupted
+		}
+		logger.Error("verify failed", "error", err)
+		return shutdown.ExitError
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logger.Error("failed to write the report", "error", err)
+		return shutdown.ExitError
+	}
+	if !report.Consistent() {
+		return exitIssues
+	}
+	return shutdown.ExitOK
+}
+
+// verify checks the heights between from and to, repairing the issues found when asked to
+func verify(ctx context.Context, pg store.DB, bc *node.BitcoinClient, sc *node.SpacesClient, from int32, to int32, repair bool) (*store.VerifyReport, error) {
+	if to == -1 {
+		var err error
+		if to, err = store.SyncedHeight(ctx, db.New(pg)); err != nil {
+			return nil, err
+		}
+	}
+	logger.Info("verifying blocks", "from_height", from, "to_height", to, "repair", repair)
+
+	report, err := store.VerifyBlocks(ctx, pg, bc, sc, from, to, activationBlock)
+	if err != nil {
+		return nil, err
+	}
+	if repair {
+		if err := store.RepairBlocks(ctx, pg, bc, sc, report, activationBlock, leaderLockKey); err != nil {
+			return nil, err
+		}
+	}
+	// after the block repairs, which store the blocks without their root anchor
+	if err := store.VerifyRootAnchors(ctx, pg, sc, report); err != nil {
+		return nil, err
+	}
+	if repair {
+		if err := store.RepairRootAnchors(ctx, pg, sc, report); err != nil {
+			return nil, err
+		}
+	}
+	logger.Info("verified blocks", "blocks", report.BlocksChecked, "issues", len(report.Issues), "consistent", report.Consistent())
+	return report, nil
+}
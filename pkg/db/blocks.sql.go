@@ -11,6 +11,30 @@ import (
 	"github.com/spacesprotocol/explorer-indexer/pkg/types"
 )
 
+const countRowsAfterHeight = `-- name: CountRowsAfterHeight :one
+SELECT
+  (SELECT COUNT(*) FROM blocks WHERE blocks.height > $1::integer)::integer AS blocks,
+  (SELECT COUNT(*) FROM transactions
+    INNER JOIN blocks ON (blocks.hash = transactions.block_hash)
+    WHERE blocks.height > $1::integer)::integer AS transactions,
+  (SELECT COUNT(*) FROM vmetaouts
+    INNER JOIN blocks ON (blocks.hash = vmetaouts.block_hash)
+    WHERE blocks.height > $1::integer)::integer AS vmetaouts
+`
+
+type CountRowsAfterHeightRow struct {
+	Blocks       int32
+	Transactions int32
+	Vmetaouts    int32
+}
+
+func (q *Queries) CountRowsAfterHeight(ctx context.Context, height int32) (CountRowsAfterHeightRow, error) {
+	row := q.db.QueryRow(ctx, countRowsAfterHeight, height)
+	var i CountRowsAfterHeightRow
+	err := row.Scan(&i.Blocks, &i.Transactions, &i.Vmetaouts)
+	return i, err
+}
+
 const deleteBlocksAfterHeight = `-- name: DeleteBlocksAfterHeight :exec
 DELETE FROM blocks
 WHERE height > $1
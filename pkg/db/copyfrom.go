@@ -52,3 +52,94 @@ func (r iteratorForInsertBatchTransactions) Err() error {
 func (q *Queries) InsertBatchTransactions(ctx context.Context, arg []InsertBatchTransactionsParams) (int64, error) {
 	return q.db.CopyFrom(ctx, []string{"transactions"}, []string{"txid", "tx_hash", "version", "size", "vsize", "weight", "locktime", "fee", "block_hash", "index", "input_count", "output_count", "total_output_value"}, &iteratorForInsertBatchTransactions{rows: arg})
 }
+
+// iteratorForInsertBatchVMetaOuts implements pgx.CopyFromSource.
+type iteratorForInsertBatchVMetaOuts struct {
+	rows                 []InsertBatchVMetaOutsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForInsertBatchVMetaOuts) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForInsertBatchVMetaOuts) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].BlockHash,
+		r.rows[0].Txid,
+		r.rows[0].Kind,
+		r.rows[0].N,
+		r.rows[0].Priority,
+		r.rows[0].Name,
+		r.rows[0].Value,
+		r.rows[0].Scriptpubkey,
+		r.rows[0].Action,
+		r.rows[0].BurnIncrement,
+		r.rows[0].Signature,
+		r.rows[0].TotalBurned,
+		r.rows[0].ClaimHeight,
+		r.rows[0].ExpireHeight,
+		r.rows[0].ScriptError,
+		r.rows[0].Reason,
+		r.rows[0].OutpointTxid,
+		r.rows[0].OutpointIndex,
+		r.rows[0].RawAction,
+		r.rows[0].ScriptErrorType,
+		r.rows[0].Data,
+		r.rows[0].DataDecoded,
+		r.rows[0].DataFormat,
+	}, nil
+}
+
+func (r iteratorForInsertBatchVMetaOuts) Err() error {
+	return nil
+}
+
+func (q *Queries) InsertBatchVMetaOuts(ctx context.Context, arg []InsertBatchVMetaOutsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"vmetaouts"}, []string{"block_hash", "txid", "kind", "n", "priority", "name", "value", "scriptpubkey", "action", "burn_increment", "signature", "total_burned", "claim_height", "expire_height", "script_error", "reason", "outpoint_txid", "outpoint_index", "raw_action", "script_error_type", "data", "data_decoded", "data_format"}, &iteratorForInsertBatchVMetaOuts{rows: arg})
+}
+
+// iteratorForInsertFeeHistogramBuckets implements pgx.CopyFromSource.
+type iteratorForInsertFeeHistogramBuckets struct {
+	rows                 []InsertFeeHistogramBucketsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForInsertFeeHistogramBuckets) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForInsertFeeHistogramBuckets) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].SnapshotID,
+		r.rows[0].FeeRateMin,
+		r.rows[0].FeeRateMax,
+		r.rows[0].TxCount,
+		r.rows[0].TotalVsize,
+		r.rows[0].TotalFees,
+	}, nil
+}
+
+func (r iteratorForInsertFeeHistogramBuckets) Err() error {
+	return nil
+}
+
+func (q *Queries) InsertFeeHistogramBuckets(ctx context.Context, arg []InsertFeeHistogramBucketsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"fee_histogram_buckets"}, []string{"snapshot_id", "fee_rate_min", "fee_rate_max", "tx_count", "total_vsize", "total_fees"}, &iteratorForInsertFeeHistogramBuckets{rows: arg})
+}
@@ -21,6 +21,9 @@ const (
 	CovenantActionROLLOUT  CovenantAction = "ROLLOUT"
 	CovenantActionREVOKE   CovenantAction = "REVOKE"
 	CovenantActionREJECT   CovenantAction = "REJECT"
+	CovenantActionOPEN     CovenantAction = "OPEN"
+	CovenantActionREGISTER CovenantAction = "REGISTER"
+	CovenantActionUNKNOWN  CovenantAction = "UNKNOWN"
 )
 
 func (e *CovenantAction) Scan(src interface{}) error {
@@ -58,6 +61,48 @@ func (ns NullCovenantAction) Value() (driver.Value, error) {
 	return string(ns.CovenantAction), nil
 }
 
+type VmetaoutKind string
+
+const (
+	VmetaoutKindOUTPUT VmetaoutKind = "OUTPUT"
+	VmetaoutKindSPEND  VmetaoutKind = "SPEND"
+)
+
+func (e *VmetaoutKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = VmetaoutKind(s)
+	case string:
+		*e = VmetaoutKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for VmetaoutKind: %T", src)
+	}
+	return nil
+}
+
+type NullVmetaoutKind struct {
+	VmetaoutKind VmetaoutKind
+	Valid        bool // Valid is true if VmetaoutKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullVmetaoutKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.VmetaoutKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.VmetaoutKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullVmetaoutKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.VmetaoutKind), nil
+}
+
 type Block struct {
 	Hash           types.Bytes
 	Size           int64
@@ -76,6 +121,115 @@ type Block struct {
 	RootAnchor     *types.Bytes
 }
 
+type FeeEstimate struct {
+	SnapshotID int64
+	ConfTarget int32
+	FeeRate    float64
+	Blocks     int32
+}
+
+type FeeHistogramBucket struct {
+	SnapshotID int64
+	FeeRateMin float64
+	FeeRateMax pgtype.Float8
+	TxCount    int32
+	TotalVsize int64
+	TotalFees  int64
+}
+
+type FeeSnapshot struct {
+	ID         int64
+	TakenAt    pgtype.Timestamptz
+	TxCount    int32
+	TotalVsize int64
+	TotalFees  int64
+}
+
+type IndexerState struct {
+	Component      string
+	Height         int32
+	BlockHash      *types.Bytes
+	TargetHeight   pgtype.Int4
+	SchemaVersion  int64
+	IndexerVersion string
+	UpdatedAt      pgtype.Timestamptz
+}
+
+type MempoolEntry struct {
+	Txid              types.Bytes
+	FirstSeen         int64
+	Vsize             int64
+	Weight            int64
+	BaseFee           int64
+	ModifiedFee       int64
+	FeeRate           float64
+	AncestorCount     int32
+	AncestorSize      int64
+	AncestorFees      int64
+	DescendantCount   int32
+	DescendantSize    int64
+	DescendantFees    int64
+	Bip125Replaceable bool
+	UpdatedAt         pgtype.Timestamptz
+}
+
+type MempoolReplacement struct {
+	ReplacedTxid  types.Bytes
+	ReplacingTxid types.Bytes
+	FeeDelta      int64
+	ReplacedAt    pgtype.Timestamptz
+}
+
+type MempoolSpend struct {
+	Txid      types.Bytes
+	PrevTxid  types.Bytes
+	PrevIndex int32
+}
+
+type MempoolTransaction struct {
+	Txid             types.Bytes
+	TxHash           types.Bytes
+	Version          int32
+	Size             int64
+	Vsize            int64
+	Weight           int64
+	Locktime         int32
+	Fee              int64
+	Seq              int64
+	InputCount       int32
+	OutputCount      int32
+	TotalOutputValue int64
+	ReplacedBy       *types.Bytes
+	AddedAt          pgtype.Timestamptz
+}
+
+type MempoolVmetaout struct {
+	Txid              types.Bytes
+	Identifier        int64
+	Priority          pgtype.Int8
+	Name              pgtype.Text
+	Reason            pgtype.Text
+	Value             pgtype.Int8
+	Scriptpubkey      *types.Bytes
+	Action            NullCovenantAction
+	BurnIncrement     pgtype.Int8
+	Signature         *types.Bytes
+	TotalBurned       pgtype.Int8
+	ClaimHeight       pgtype.Int8
+	ExpireHeight      pgtype.Int8
+	ScriptError       pgtype.Text
+	OutpointTxid      *types.Bytes
+	OutpointIndex     pgtype.Int8
+	RawAction         pgtype.Text
+	ScriptErrorType   pgtype.Text
+	Data              *types.Bytes
+	DataDecoded       pgtype.Text
+	DataFormat        pgtype.Text
+	Kind              VmetaoutKind
+	N                 pgtype.Int4
+	ScriptErrorStatus pgtype.Text
+}
+
 type Rollout struct {
 	Name   string
 	Bid    int64
@@ -99,21 +253,29 @@ type Transaction struct {
 }
 
 type Vmetaout struct {
-	BlockHash     types.Bytes
-	Txid          types.Bytes
-	Identifier    int64
-	Priority      pgtype.Int8
-	Name          pgtype.Text
-	Reason        pgtype.Text
-	Value         pgtype.Int8
-	Scriptpubkey  *types.Bytes
-	Action        NullCovenantAction
-	BurnIncrement pgtype.Int8
-	Signature     *types.Bytes
-	TotalBurned   pgtype.Int8
-	ClaimHeight   pgtype.Int8
-	ExpireHeight  pgtype.Int8
-	ScriptError   pgtype.Text
-	OutpointTxid  *types.Bytes
-	OutpointIndex pgtype.Int8
+	BlockHash         types.Bytes
+	Txid              types.Bytes
+	Identifier        int64
+	Priority          pgtype.Int8
+	Name              pgtype.Text
+	Reason            pgtype.Text
+	Value             pgtype.Int8
+	Scriptpubkey      *types.Bytes
+	Action            NullCovenantAction
+	BurnIncrement     pgtype.Int8
+	Signature         *types.Bytes
+	TotalBurned       pgtype.Int8
+	ClaimHeight       pgtype.Int8
+	ExpireHeight      pgtype.Int8
+	ScriptError       pgtype.Text
+	OutpointTxid      *types.Bytes
+	OutpointIndex     pgtype.Int8
+	Kind              VmetaoutKind
+	N                 pgtype.Int4
+	RawAction         pgtype.Text
+	ScriptErrorType   pgtype.Text
+	Data              *types.Bytes
+	DataDecoded       pgtype.Text
+	DataFormat        pgtype.Text
+	ScriptErrorStatus pgtype.Text
 }
@@ -13,8 +13,7 @@ import (
 )
 
 const deleteMempoolVmetaouts = `-- name: DeleteMempoolVmetaouts :exec
-DELETE FROM vmetaouts
-WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'
+DELETE FROM mempool_vmetaouts
 `
 
 func (q *Queries) DeleteMempoolVmetaouts(ctx context.Context) error {
@@ -23,9 +22,8 @@ func (q *Queries) DeleteMempoolVmetaouts(ctx context.Context) error {
 }
 
 const deleteMempoolVmetaoutsByTxid = `-- name: DeleteMempoolVmetaoutsByTxid :exec
-DELETE FROM vmetaouts
+DELETE FROM mempool_vmetaouts
 WHERE txid = $1
-AND block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'
 `
 
 func (q *Queries) DeleteMempoolVmetaoutsByTxid(ctx context.Context, txid types.Bytes) error {
@@ -42,29 +40,469 @@ func (q *Queries) DeleteRollouts(ctx context.Context) error {
 	return err
 }
 
-const insertRollout = `-- name: InsertRollout :exec
-INSERT INTO rollouts (
-    name,
-    bid,
-    target
-)
-VALUES ($1, $2, $3)
+const deleteVMetaOutsByBlockHash = `-- name: DeleteVMetaOutsByBlockHash :exec
+DELETE FROM vmetaouts WHERE block_hash = $1
 `
 
-type InsertRolloutParams struct {
-	Name   string
-	Bid    int64
-	Target int64
+func (q *Queries) DeleteVMetaOutsByBlockHash(ctx context.Context, blockHash types.Bytes) error {
+	_, err := q.db.Exec(ctx, deleteVMetaOutsByBlockHash, blockHash)
+	return err
 }
 
-func (q *Queries) InsertRollout(ctx context.Context, arg InsertRolloutParams) error {
-	_, err := q.db.Exec(ctx, insertRollout, arg.Name, arg.Bid, arg.Target)
-	return err
+const getLatestSpaceStates = `-- name: GetLatestSpaceStates :many
+SELECT
+    states.block_hash,
+    states.txid,
+    states.identifier,
+    states.priority,
+    states.name,
+    states.reason,
+    states.value,
+    states.scriptpubkey,
+    states.action,
+    states.burn_increment,
+    states.signature,
+    states.total_burned,
+    states.claim_height,
+    states.expire_height,
+    states.script_error,
+    states.outpoint_txid,
+    states.outpoint_index,
+    states.kind,
+    states.n,
+    states.raw_action,
+    states.script_error_type,
+    latest_transfer.data,
+    latest_transfer.data_decoded,
+    latest_transfer.data_format,
+    states.block_height
+FROM (
+    SELECT DISTINCT ON (vmetaouts.name) vmetaouts.block_hash, vmetaouts.txid, vmetaouts.identifier, vmetaouts.priority, vmetaouts.name, vmetaouts.reason, vmetaouts.value, vmetaouts.scriptpubkey, vmetaouts.action, vmetaouts.burn_increment, vmetaouts.signature, vmetaouts.total_burned, vmetaouts.claim_height, vmetaouts.expire_height, vmetaouts.script_error, vmetaouts.outpoint_txid, vmetaouts.outpoint_index, vmetaouts.kind, vmetaouts.n, vmetaouts.raw_action, vmetaouts.script_error_type, vmetaouts.data, vmetaouts.data_decoded, vmetaouts.data_format, vmetaouts.script_error_status, blocks.height AS block_height
+    FROM vmetaouts
+        INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+        INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+    WHERE vmetaouts.name = ANY($1::text[])
+    AND NOT blocks.orphan
+    AND blocks.height >= 0
+    AND vmetaouts.action IS NOT NULL
+    AND vmetaouts.action <> 'REJECT'
+    ORDER BY vmetaouts.name, blocks.height DESC, transactions.index DESC, vmetaouts.identifier DESC
+) AS states
+    -- only a transfer sets the data of a space, as in GetSpaceDataHistory
+    LEFT JOIN LATERAL (
+        SELECT vmetaouts.data, vmetaouts.data_decoded, vmetaouts.data_format
+        FROM vmetaouts
+            INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+            INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+        WHERE vmetaouts.name = states.name
+        AND vmetaouts.action = 'TRANSFER'
+        AND NOT blocks.orphan
+        ORDER BY blocks.height DESC, transactions.index DESC, vmetaouts.n DESC
+        LIMIT 1
+    ) AS latest_transfer ON true
+ORDER BY states.name
+`
+
+type GetLatestSpaceStatesRow struct {
+	BlockHash       types.Bytes
+	Txid            types.Bytes
+	Identifier      int64
+	Priority        pgtype.Int8
+	Name            pgtype.Text
+	Reason          pgtype.Text
+	Value           pgtype.Int8
+	Scriptpubkey    *types.Bytes
+	Action          NullCovenantAction
+	BurnIncrement   pgtype.Int8
+	Signature       *types.Bytes
+	TotalBurned     pgtype.Int8
+	ClaimHeight     pgtype.Int8
+	ExpireHeight    pgtype.Int8
+	ScriptError     pgtype.Text
+	OutpointTxid    *types.Bytes
+	OutpointIndex   pgtype.Int8
+	Kind            VmetaoutKind
+	N               pgtype.Int4
+	RawAction       pgtype.Text
+	ScriptErrorType pgtype.Text
+	Data            *types.Bytes
+	DataDecoded     pgtype.Text
+	DataFormat      pgtype.Text
+	BlockHeight     int32
+}
+
+func (q *Queries) GetLatestSpaceStates(ctx context.Context, dollar_1 []string) ([]GetLatestSpaceStatesRow, error) {
+	rows, err := q.db.Query(ctx, getLatestSpaceStates, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetLatestSpaceStatesRow{}
+	for rows.Next() {
+		var i GetLatestSpaceStatesRow
+		if err := rows.Scan(
+			&i.BlockHash,
+			&i.Txid,
+			&i.Identifier,
+			&i.Priority,
+			&i.Name,
+			&i.Reason,
+			&i.Value,
+			&i.Scriptpubkey,
+			&i.Action,
+			&i.BurnIncrement,
+			&i.Signature,
+			&i.TotalBurned,
+			&i.ClaimHeight,
+			&i.ExpireHeight,
+			&i.ScriptError,
+			&i.OutpointTxid,
+			&i.OutpointIndex,
+			&i.Kind,
+			&i.N,
+			&i.RawAction,
+			&i.ScriptErrorType,
+			&i.Data,
+			&i.DataDecoded,
+			&i.DataFormat,
+			&i.BlockHeight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const insertVMetaOut = `-- name: InsertVMetaOut :exec
-INSERT INTO vmetaouts (
-    block_hash,
+const getMempoolSpaceActions = `-- name: GetMempoolSpaceActions :many
+SELECT mempool_vmetaouts.txid, mempool_vmetaouts.identifier, mempool_vmetaouts.priority, mempool_vmetaouts.name, mempool_vmetaouts.reason, mempool_vmetaouts.value, mempool_vmetaouts.scriptpubkey, mempool_vmetaouts.action, mempool_vmetaouts.burn_increment, mempool_vmetaouts.signature, mempool_vmetaouts.total_burned, mempool_vmetaouts.claim_height, mempool_vmetaouts.expire_height, mempool_vmetaouts.script_error, mempool_vmetaouts.outpoint_txid, mempool_vmetaouts.outpoint_index, mempool_vmetaouts.raw_action, mempool_vmetaouts.script_error_type, mempool_vmetaouts.data, mempool_vmetaouts.data_decoded, mempool_vmetaouts.data_format, mempool_vmetaouts.kind, mempool_vmetaouts.n, mempool_vmetaouts.script_error_status, mempool_transactions.seq
+FROM mempool_vmetaouts
+    INNER JOIN mempool_transactions ON (mempool_vmetaouts.txid = mempool_transactions.txid)
+WHERE mempool_transactions.replaced_by IS NULL
+AND mempool_vmetaouts.name IS NOT NULL
+ORDER BY mempool_vmetaouts.name, mempool_transactions.seq, mempool_vmetaouts.identifier
+`
+
+type GetMempoolSpaceActionsRow struct {
+	Txid              types.Bytes
+	Identifier        int64
+	Priority          pgtype.Int8
+	Name              pgtype.Text
+	Reason            pgtype.Text
+	Value             pgtype.Int8
+	Scriptpubkey      *types.Bytes
+	Action            NullCovenantAction
+	BurnIncrement     pgtype.Int8
+	Signature         *types.Bytes
+	TotalBurned       pgtype.Int8
+	ClaimHeight       pgtype.Int8
+	ExpireHeight      pgtype.Int8
+	ScriptError       pgtype.Text
+	OutpointTxid      *types.Bytes
+	OutpointIndex     pgtype.Int8
+	RawAction         pgtype.Text
+	ScriptErrorType   pgtype.Text
+	Data              *types.Bytes
+	DataDecoded       pgtype.Text
+	DataFormat        pgtype.Text
+	Kind              VmetaoutKind
+	N                 pgtype.Int4
+	ScriptErrorStatus pgtype.Text
+	Seq               int64
+}
+
+func (q *Queries) GetMempoolSpaceActions(ctx context.Context) ([]GetMempoolSpaceActionsRow, error) {
+	rows, err := q.db.Query(ctx, getMempoolSpaceActions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetMempoolSpaceActionsRow{}
+	for rows.Next() {
+		var i GetMempoolSpaceActionsRow
+		if err := rows.Scan(
+			&i.Txid,
+			&i.Identifier,
+			&i.Priority,
+			&i.Name,
+			&i.Reason,
+			&i.Value,
+			&i.Scriptpubkey,
+			&i.Action,
+			&i.BurnIncrement,
+			&i.Signature,
+			&i.TotalBurned,
+			&i.ClaimHeight,
+			&i.ExpireHeight,
+			&i.ScriptError,
+			&i.OutpointTxid,
+			&i.OutpointIndex,
+			&i.RawAction,
+			&i.ScriptErrorType,
+			&i.Data,
+			&i.DataDecoded,
+			&i.DataFormat,
+			&i.Kind,
+			&i.N,
+			&i.ScriptErrorStatus,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMempoolSpaceActionsByNames = `-- name: GetMempoolSpaceActionsByNames :many
+SELECT mempool_vmetaouts.txid, mempool_vmetaouts.identifier, mempool_vmetaouts.priority, mempool_vmetaouts.name, mempool_vmetaouts.reason, mempool_vmetaouts.value, mempool_vmetaouts.scriptpubkey, mempool_vmetaouts.action, mempool_vmetaouts.burn_increment, mempool_vmetaouts.signature, mempool_vmetaouts.total_burned, mempool_vmetaouts.claim_height, mempool_vmetaouts.expire_height, mempool_vmetaouts.script_error, mempool_vmetaouts.outpoint_txid, mempool_vmetaouts.outpoint_index, mempool_vmetaouts.raw_action, mempool_vmetaouts.script_error_type, mempool_vmetaouts.data, mempool_vmetaouts.data_decoded, mempool_vmetaouts.data_format, mempool_vmetaouts.kind, mempool_vmetaouts.n, mempool_vmetaouts.script_error_status, mempool_transactions.seq
+FROM mempool_vmetaouts
+    INNER JOIN mempool_transactions ON (mempool_vmetaouts.txid = mempool_transactions.txid)
+WHERE mempool_transactions.replaced_by IS NULL
+AND mempool_vmetaouts.name = ANY($1::text[])
+ORDER BY mempool_vmetaouts.name, mempool_transactions.seq, mempool_vmetaouts.identifier
+`
+
+type GetMempoolSpaceActionsByNamesRow struct {
+	Txid              types.Bytes
+	Identifier        int64
+	Priority          pgtype.Int8
+	Name              pgtype.Text
+	Reason            pgtype.Text
+	Value             pgtype.Int8
+	Scriptpubkey      *types.Bytes
+	Action            NullCovenantAction
+	BurnIncrement     pgtype.Int8
+	Signature         *types.Bytes
+	TotalBurned       pgtype.Int8
+	ClaimHeight       pgtype.Int8
+	ExpireHeight      pgtype.Int8
+	ScriptError       pgtype.Text
+	OutpointTxid      *types.Bytes
+	OutpointIndex     pgtype.Int8
+	RawAction         pgtype.Text
+	ScriptErrorType   pgtype.Text
+	Data              *types.Bytes
+	DataDecoded       pgtype.Text
+	DataFormat        pgtype.Text
+	Kind              VmetaoutKind
+	N                 pgtype.Int4
+	ScriptErrorStatus pgtype.Text
+	Seq               int64
+}
+
+func (q *Queries) GetMempoolSpaceActionsByNames(ctx context.Context, dollar_1 []string) ([]GetMempoolSpaceActionsByNamesRow, error) {
+	rows, err := q.db.Query(ctx, getMempoolSpaceActionsByNames, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetMempoolSpaceActionsByNamesRow{}
+	for rows.Next() {
+		var i GetMempoolSpaceActionsByNamesRow
+		if err := rows.Scan(
+			&i.Txid,
+			&i.Identifier,
+			&i.Priority,
+			&i.Name,
+			&i.Reason,
+			&i.Value,
+			&i.Scriptpubkey,
+			&i.Action,
+			&i.BurnIncrement,
+			&i.Signature,
+			&i.TotalBurned,
+			&i.ClaimHeight,
+			&i.ExpireHeight,
+			&i.ScriptError,
+			&i.OutpointTxid,
+			&i.OutpointIndex,
+			&i.RawAction,
+			&i.ScriptErrorType,
+			&i.Data,
+			&i.DataDecoded,
+			&i.DataFormat,
+			&i.Kind,
+			&i.N,
+			&i.ScriptErrorStatus,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSpaceBidHistory = `-- name: GetSpaceBidHistory :many
+SELECT txid, block_hash, burn_increment, total_burned, block_height, status, replaced_by, position
+FROM (
+    SELECT
+        vmetaouts.txid,
+        vmetaouts.block_hash,
+        vmetaouts.burn_increment,
+        vmetaouts.total_burned,
+        blocks.height AS block_height,
+        'confirmed'::text AS status,
+        NULL::bytea AS replaced_by,
+        transactions.index::bigint AS position
+    FROM vmetaouts
+        INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+        INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+    WHERE vmetaouts.name = $1
+    AND vmetaouts.action = 'BID'
+    AND NOT blocks.orphan
+    UNION ALL
+    SELECT
+        mempool_vmetaouts.txid,
+        NULL::bytea AS block_hash,
+        mempool_vmetaouts.burn_increment,
+        mempool_vmetaouts.total_burned,
+        -1 AS block_height,
+        (CASE
+            WHEN mempool_transactions.replaced_by IS NOT NULL THEN 'replaced'
+            ELSE 'pending'
+        END)::text AS status,
+        mempool_transactions.replaced_by,
+        mempool_transactions.seq AS position
+    FROM mempool_vmetaouts
+        INNER JOIN mempool_transactions ON (mempool_vmetaouts.txid = mempool_transactions.txid)
+    WHERE mempool_vmetaouts.name = $1
+    AND mempool_vmetaouts.action = 'BID'
+) AS bids
+ORDER BY block_height = -1 DESC, block_height DESC, position DESC
+`
+
+type GetSpaceBidHistoryRow struct {
+	Txid          types.Bytes
+	BlockHash     types.Bytes
+	BurnIncrement pgtype.Int8
+	TotalBurned   pgtype.Int8
+	BlockHeight   int32
+	Status        string
+	ReplacedBy    *types.Bytes
+	Position      int64
+}
+
+func (q *Queries) GetSpaceBidHistory(ctx context.Context, name pgtype.Text) ([]GetSpaceBidHistoryRow, error) {
+	rows, err := q.db.Query(ctx, getSpaceBidHistory, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetSpaceBidHistoryRow{}
+	for rows.Next() {
+		var i GetSpaceBidHistoryRow
+		if err := rows.Scan(
+			&i.Txid,
+			&i.BlockHash,
+			&i.BurnIncrement,
+			&i.TotalBurned,
+			&i.BlockHeight,
+			&i.Status,
+			&i.ReplacedBy,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSpaceDataHistory = `-- name: GetSpaceDataHistory :many
+SELECT
+    vmetaouts.txid,
+    vmetaouts.block_hash,
+    blocks.height AS block_height,
+    vmetaouts.data,
+    vmetaouts.data_decoded,
+    vmetaouts.data_format
+FROM vmetaouts
+    INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+    INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+WHERE vmetaouts.name = $1
+AND vmetaouts.action = 'TRANSFER'
+AND NOT blocks.orphan
+ORDER BY blocks.height DESC, transactions.index DESC, vmetaouts.n DESC
+`
+
+type GetSpaceDataHistoryRow struct {
+	Txid        types.Bytes
+	BlockHash   types.Bytes
+	BlockHeight int32
+	Data        *types.Bytes
+	DataDecoded pgtype.Text
+	DataFormat  pgtype.Text
+}
+
+func (q *Queries) GetSpaceDataHistory(ctx context.Context, name pgtype.Text) ([]GetSpaceDataHistoryRow, error) {
+	rows, err := q.db.Query(ctx, getSpaceDataHistory, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetSpaceDataHistoryRow{}
+	for rows.Next() {
+		var i GetSpaceDataHistoryRow
+		if err := rows.Scan(
+			&i.Txid,
+			&i.BlockHash,
+			&i.BlockHeight,
+			&i.Data,
+			&i.DataDecoded,
+			&i.DataFormat,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type InsertBatchVMetaOutsParams struct {
+	BlockHash       types.Bytes
+	Txid            types.Bytes
+	Kind            VmetaoutKind
+	N               pgtype.Int4
+	Priority        pgtype.Int8
+	Name            pgtype.Text
+	Value           pgtype.Int8
+	Scriptpubkey    *types.Bytes
+	Action          NullCovenantAction
+	BurnIncrement   pgtype.Int8
+	Signature       *types.Bytes
+	TotalBurned     pgtype.Int8
+	ClaimHeight     pgtype.Int8
+	ExpireHeight    pgtype.Int8
+	ScriptError     pgtype.Text
+	Reason          pgtype.Text
+	OutpointTxid    *types.Bytes
+	OutpointIndex   pgtype.Int8
+	RawAction       pgtype.Text
+	ScriptErrorType pgtype.Text
+	Data            *types.Bytes
+	DataDecoded     pgtype.Text
+	DataFormat      pgtype.Text
+}
+
+const insertMempoolVMetaOut = `-- name: InsertMempoolVMetaOut :exec
+INSERT INTO mempool_vmetaouts (
     txid,
     priority,
     name,
@@ -79,33 +517,45 @@ INSERT INTO vmetaouts (
     script_error,
     reason,
     outpoint_txid,
-    outpoint_index
+    outpoint_index,
+    raw_action,
+    script_error_type,
+    data,
+    data_decoded,
+    data_format,
+    kind,
+    n
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
 `
 
-type InsertVMetaOutParams struct {
-	BlockHash     types.Bytes
-	Txid          types.Bytes
-	Priority      pgtype.Int8
-	Name          pgtype.Text
-	Value         pgtype.Int8
-	Scriptpubkey  *types.Bytes
-	Action        NullCovenantAction
-	BurnIncrement pgtype.Int8
-	Signature     *types.Bytes
-	TotalBurned   pgtype.Int8
-	ClaimHeight   pgtype.Int8
-	ExpireHeight  pgtype.Int8
-	ScriptError   pgtype.Text
-	Reason        pgtype.Text
-	OutpointTxid  *types.Bytes
-	OutpointIndex pgtype.Int8
+type InsertMempoolVMetaOutParams struct {
+	Txid            types.Bytes
+	Priority        pgtype.Int8
+	Name            pgtype.Text
+	Value           pgtype.Int8
+	Scriptpubkey    *types.Bytes
+	Action          NullCovenantAction
+	BurnIncrement   pgtype.Int8
+	Signature       *types.Bytes
+	TotalBurned     pgtype.Int8
+	ClaimHeight     pgtype.Int8
+	ExpireHeight    pgtype.Int8
+	ScriptError     pgtype.Text
+	Reason          pgtype.Text
+	OutpointTxid    *types.Bytes
+	OutpointIndex   pgtype.Int8
+	RawAction       pgtype.Text
+	ScriptErrorType pgtype.Text
+	Data            *types.Bytes
+	DataDecoded     pgtype.Text
+	DataFormat      pgtype.Text
+	Kind            VmetaoutKind
+	N               pgtype.Int4
 }
 
-func (q *Queries) InsertVMetaOut(ctx context.Context, arg InsertVMetaOutParams) error {
-	_, err := q.db.Exec(ctx, insertVMetaOut,
-		arg.BlockHash,
+func (q *Queries) InsertMempoolVMetaOut(ctx context.Context, arg InsertMempoolVMetaOutParams) error {
+	_, err := q.db.Exec(ctx, insertMempoolVMetaOut,
 		arg.Txid,
 		arg.Priority,
 		arg.Name,
@@ -121,6 +571,33 @@ func (q *Queries) InsertVMetaOut(ctx context.Context, arg InsertVMetaOutParams)
 		arg.Reason,
 		arg.OutpointTxid,
 		arg.OutpointIndex,
+		arg.RawAction,
+		arg.ScriptErrorType,
+		arg.Data,
+		arg.DataDecoded,
+		arg.DataFormat,
+		arg.Kind,
+		arg.N,
 	)
 	return err
 }
+
+const insertRollout = `-- name: InsertRollout :exec
+INSERT INTO rollouts (
+    name,
+    bid,
+    target
+)
+VALUES ($1, $2, $3)
+`
+
+type InsertRolloutParams struct {
+	Name   string
+	Bid    int64
+	Target int64
+}
+
+func (q *Queries) InsertRollout(ctx context.Context, arg InsertRolloutParams) error {
+	_, err := q.db.Exec(ctx, insertRollout, arg.Name, arg.Bid, arg.Target)
+	return err
+}
@@ -0,0 +1,203 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/shutdown"
+)
+
+// Elector elects one leader among the processes sharing a database with a session level
+// postgres advisory lock. The lock is held on a dedicated pool connection, so it is released
+// by postgres as soon as the leader process or its connection dies.
+type Elector struct {
+	pool     *pgxpool.Pool
+	key      int64
+	id       string
+	interval time.Duration
+	leading  atomic.Bool
+	// backend pid of the lock connection, 0 while not leading
+	pid atomic.Int32
+}
+
+// ErrNotLeader is returned by Fence once the elector no longer holds the lock
+var ErrNotLeader = errors.New("not the sync leader")
+
+// LockKey returns the advisory lock key from LEADER_LOCK_KEY, all the sync replicas sharing a database
+// must use the same key and the commands that must not run next to a leader check it
+func LockKey() int64 {
+	if key := os.Getenv("LEADER_LOCK_KEY"); key != "" {
+		if k, err := strconv.ParseInt(key, 10, 64); err == nil {
+			return k
+		}
+	}
+	return 0x73796e63 // "sync"
+}
+
+// DefaultID identifies the process as host:pid
+func DefaultID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// New returns an elector campaigning for the lock key, interval is how often a standby retries
+// the lock and how often the leader checks it still holds it
+func New(pool *pgxpool.Pool, key int64, id string, interval time.Duration) *Elector {
+	return &Elector{pool: pool, key: key, id: id, interval: interval}
+}
+
+func (e *Elector) ID() string {
+	return e.id
+}
+
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Leader returns the id of the current leader, empty if there is none
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	holder, err := db.New(e.pool).GetAdvisoryLockHolder(ctx, e.key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return holder.String, err
+}
+
+// Fence fails with ErrNotLeader unless the lock connection still holds the lock. Call it with the
+// queries of a write transaction right before committing, so a leader that lost the lock
+// while the transaction ran does not commit next to the new leader.
+func (e *Elector) Fence(ctx context.Context, q *db.Queries) error {
+	pid := e.pid.Load()
+	if pid == 0 {
+		return ErrNotLeader
+	}
+	held, err := q.HoldsAdvisoryLock(ctx, db.HoldsAdvisoryLockParams{Key: e.key, Pid: pid})
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// LockXact takes the lock key until the transaction of q ends, so no leader starts meanwhile.
+// It fails while a leader holds the key, the commands writing without being the leader call it.
+func LockXact(ctx context.Context, q *db.Queries, key int64) error {
+	locked, err := q.TryAdvisoryXactLock(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		holder, _ := q.GetAdvisoryLockHolder(ctx, key)
+		return fmt.Errorf("sync leader %q is running, stop it first", holder.String)
+	}
+	return nil
+}
+
+// XactFence returns a fence taking the lock key with LockXact, for the write transactions of the
+// commands that must not run next to a leader
+func XactFence(key int64) func(ctx context.Context, q *db.Queries) error {
+	return func(ctx context.Context, q *db.Queries) error {
+		return LockXact(ctx, q, key)
+	}
+}
+
+// Run campaigns for leadership until ctx is done. Every time the lock is won lead is called
+// with a context that is cancelled once leadership is lost, the lock is released after lead returns.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	logger := logging.Component("leader").With("id", e.id)
+	for ctx.Err() == nil {
+		conn, err := e.acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("leader election failed", "error", err)
+			}
+			shutdown.Sleep(ctx, e.interval)
+			continue
+		}
+		if conn == nil {
+			logger.Debug("standing by, lock held by another instance")
+			shutdown.Sleep(ctx, e.interval)
+			continue
+		}
+
+		logger.Info("became leader")
+		e.leading.Store(true)
+		leadCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			lead(leadCtx)
+		}()
+
+		if err := e.hold(leadCtx, conn); err != nil {
+			logger.Error("lost leadership", "error", err)
+		}
+		cancel()
+		<-done
+
+		e.leading.Store(false)
+		e.pid.Store(0)
+		e.release(conn)
+		logger.Info("stepped down")
+	}
+}
+
+// acquire returns the connection holding the lock, or nil if another instance holds it
+func (e *Elector) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := e.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := db.New(conn)
+	if err := q.SetApplicationName(ctx, e.id); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	locked, err := q.TryAdvisoryLock(ctx, e.key)
+	if err != nil || !locked {
+		conn.Release()
+		return nil, err
+	}
+	e.pid.Store(int32(conn.Conn().PgConn().PID()))
+	return conn, nil
+}
+
+// hold checks the lock connection every interval until ctx is done,
+// it returns an error if the connection, and with it the lock, is lost
+func (e *Elector) hold(ctx context.Context, conn *pgxpool.Conn) error {
+	for shutdown.Sleep(ctx, e.interval) {
+		pingCtx, cancel := context.WithTimeout(ctx, e.interval)
+		err := conn.Ping(pingCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release unlocks and returns the connection to the pool, a broken connection is closed instead
+func (e *Elector) release(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+	if _, err := db.New(conn).AdvisoryUnlock(ctx, e.key); err != nil {
+		// closing the session drops the lock
+		conn.Hijack().Close(ctx)
+		return
+	}
+	conn.Release()
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Setup installs the default slog logger configured from LOG_FORMAT (text or json)
+// and LOG_LEVEL (debug, info, warn or error) and returns a logger tagged with the component name.
+// The commands keep their logger in a package level variable that starts as slog.Default()
+// and is replaced by the one Setup returns once main starts.
+func Setup(component string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: getLevel()}
+
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "json") {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return Component(component)
+}
+
+// Component returns the default logger tagged with the component name
+func Component(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
+
+func getLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
@@ -0,0 +1,350 @@
+// Package mempool mirrors the mempool of the bitcoin node into the db, along with the
+// spaces outputs spaced reports for its txs and the fee market.
+package mempool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// Syncer runs the mempool passes. The node RPCs run unlocked, chainMu is held around the db
+// transactions so a block confirming txids never commits in the middle of the mempool cleanup;
+// a txid a block confirmed meanwhile is dropped again by the next pass.
+type Syncer struct {
+	pg      store.DB
+	bc      *node.BitcoinClient
+	sc      *node.SpacesClient
+	chainMu sync.Locker
+	logger  *slog.Logger
+
+	// confirmation targets stored with every fee snapshot
+	feeTargets []int
+	// how long fee snapshots are kept
+	feeRetention time.Duration
+	// minimum time between two fee snapshots
+	feeInterval     time.Duration
+	lastFeeSnapshot time.Time
+}
+
+// New returns a syncer sharing chainMu with whatever stores the blocks
+func New(pg store.DB, bc *node.BitcoinClient, sc *node.SpacesClient, chainMu sync.Locker, feeTargets []int, feeRetention time.Duration, feeInterval time.Duration) *Syncer {
+	return &Syncer{
+		pg:           pg,
+		bc:           bc,
+		sc:           sc,
+		chainMu:      chainMu,
+		logger:       logging.Component("mempool"),
+		feeTargets:   feeTargets,
+		feeRetention: feeRetention,
+		feeInterval:  feeInterval,
+	}
+}
+
+// Sync mirrors the node's mempool into the db: the txs that left it are removed or kept as replaced,
+// a fee snapshot is stored and the new txs are stored with their spaces outputs
+func (s *Syncer) Sync(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "sync.mempool")
+	defer func() { tracing.End(span, err) }()
+
+	mempoolEntries, err := s.bc.GetMempoolEntries(ctx)
+	if err != nil {
+		return err
+	}
+	currentGroups := node.GroupMempoolTxs(mempoolEntries)
+	metrics.MempoolNodeTxs.Set(float64(len(mempoolEntries)))
+
+	q := db.New(s.pg)
+	existingTxidsBytes, err := q.GetMempoolTxids(ctx)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("fetched mempools", "node_txs", len(mempoolEntries), "db_txs", len(existingTxidsBytes))
+
+	existingTxMap := make(map[string]Bytes, len(existingTxidsBytes))
+	for _, txid := range existingTxidsBytes {
+		existingTxMap[txid.String()] = txid
+	}
+
+	if err := s.cleanupMempoolTxs(ctx, mempoolEntries, existingTxMap); err != nil {
+		return err
+	}
+
+	// a missing fee snapshot should not hold back the mempool txs
+	if err := s.syncFeeMarket(ctx, mempoolEntries); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		metrics.FeeMarketFailures.Inc()
+		s.logger.Error("failed to sync fee market", "error", err)
+	}
+
+	// Pre-filter groups that need processing
+	var groupsToProcess [][]string
+	for _, group := range currentGroups {
+		if len(group) == 0 {
+			continue
+		}
+		// Check if the dependent transaction (last in group) exists
+		lastTxID := group[len(group)-1]
+		if _, exists := existingTxMap[lastTxID]; !exists {
+			groupsToProcess = append(groupsToProcess, group)
+		}
+	}
+
+	s.logger.Info("filtered mempool groups", "to_process", len(groupsToProcess), "total", len(currentGroups))
+
+	// Process only the filtered groups
+	for groupIndex, txGroup := range groupsToProcess {
+		if groupIndex%50 == 0 {
+			s.logger.Debug("processing mempool group", "group", groupIndex, "total", len(groupsToProcess))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := s.processTxGroup(ctx, txGroup); err != nil {
+			return err
+		}
+	}
+
+	dbMempoolSize, err := q.CountMempoolTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.MempoolDBTxs.Set(float64(dbMempoolSize))
+	return nil
+
+}
+
+// removes txs that left the node's mempool and refreshes the mempool entries in one db transaction,
+// replaced txs are kept and marked as such until the tx that replaced them leaves the mempool
+func (s *Syncer) cleanupMempoolTxs(ctx context.Context, mempoolEntries map[string]node.MempoolTx, existingTxMap map[string]Bytes) error {
+	var toDelete []Bytes
+	for txidStr, txidBytes := range existingTxMap {
+		if _, exists := mempoolEntries[txidStr]; !exists {
+			toDelete = append(toDelete, txidBytes)
+		}
+	}
+
+	// the node is asked for the replacements before the chain lock is taken
+	replacements, err := findMempoolReplacements(ctx, db.New(s.pg), s.bc, toDelete)
+	if err != nil {
+		return err
+	}
+
+	s.chainMu.Lock()
+	defer s.chainMu.Unlock()
+
+	ctx, sqlTx, err := tracing.BeginTx(ctx, s.pg, "cleanupMempoolTxs")
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback(ctx)
+
+	q := db.New(sqlTx)
+
+	s.logger.Info("deleting txs from db mempool", "txs", len(toDelete))
+	if len(toDelete) > 0 {
+		// Delete in chunks to avoid overwhelming the database
+		chunkSize := 500
+		for i := 0; i < len(toDelete); i += chunkSize {
+			end := i + chunkSize
+			if end > len(toDelete) {
+				end = len(toDelete)
+			}
+			chunk := toDelete[i:end]
+
+			deleted := make([]Bytes, 0, len(chunk))
+			for _, txid := range chunk {
+				replacingTxid, isReplaced := replacements[txid.String()]
+				if !isReplaced {
+					deleted = append(deleted, txid)
+					continue
+				}
+				replacing, exists := mempoolEntries[replacingTxid.String()]
+				if !exists {
+					deleted = append(deleted, txid)
+					continue
+				}
+				if err := store.StoreMempoolReplacement(ctx, q, txid, replacingTxid, replacing); err != nil {
+					return err
+				}
+			}
+			if len(deleted) < len(chunk) {
+				s.logger.Info("found replaced mempool txs", "replaced", len(chunk)-len(deleted), "chunk_start", i+1, "chunk_end", end)
+			}
+
+			s.logger.Debug("deleting mempool txs chunk", "chunk_start", i+1, "chunk_end", end, "total", len(toDelete))
+			// the txs kept as replaced by a tx that is gone now go with it
+			if err := q.DeleteMempoolTransactionsReplacedBy(ctx, deleted); err != nil {
+				return err
+			}
+			if err := q.DeleteMempoolTransactionsByTxids(ctx, deleted); err != nil {
+				return err
+			}
+			if err := q.DeleteMempoolEntriesByTxids(ctx, chunk); err != nil {
+				return err
+			}
+		}
+
+		s.logger.Info("deleted txs from db mempool", "txs", len(toDelete))
+	}
+
+	if err := store.StoreMempoolEntries(ctx, q, mempoolEntries); err != nil {
+		return err
+	}
+	if err := store.CopyState(ctx, q, store.ComponentMempool); err != nil {
+		return err
+	}
+	return sqlTx.Commit(ctx)
+}
+
+// stores the mempool fee rate histogram and the node's fee estimates, dropping snapshots past retention.
+// Passes closer than feeInterval to the last snapshot store none
+func (s *Syncer) syncFeeMarket(ctx context.Context, mempoolEntries map[string]node.MempoolTx) error {
+	if !s.lastFeeSnapshot.IsZero() && time.Since(s.lastFeeSnapshot) < s.feeInterval {
+		return nil
+	}
+	estimates := make(map[int]*node.FeeEstimate, len(s.feeTargets))
+	for _, target := range s.feeTargets {
+		estimate, err := s.bc.EstimateSmartFee(ctx, target)
+		if err != nil {
+			return err
+		}
+		estimates[target] = estimate
+	}
+
+	ctx, sqlTx, err := tracing.BeginTx(ctx, s.pg, "syncFeeMarket")
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback(ctx)
+
+	q := db.New(sqlTx)
+	if err := store.StoreFeeSnapshot(ctx, q, mempoolEntries, estimates); err != nil {
+		return err
+	}
+	if err := store.PruneFeeSnapshots(ctx, q, s.feeRetention); err != nil {
+		return err
+	}
+	if err := sqlTx.Commit(ctx); err != nil {
+		return err
+	}
+	s.lastFeeSnapshot = time.Now()
+	return nil
+}
+
+// matches the inputs of txs that left the node's mempool against the node's mempool,
+// a tx whose input is spent by another mempool tx has been replaced by it
+func findMempoolReplacements(ctx context.Context, q *db.Queries, bc *node.BitcoinClient, txids []Bytes) (map[string]Bytes, error) {
+	replacements := make(map[string]Bytes)
+	if len(txids) == 0 {
+		return replacements, nil
+	}
+	spends, err := q.GetMempoolSpendsByTxids(ctx, txids)
+	if err != nil {
+		return nil, err
+	}
+	if len(spends) == 0 {
+		return replacements, nil
+	}
+
+	outpoints := make([]node.Outpoint, 0, len(spends))
+	for _, spend := range spends {
+		outpoints = append(outpoints, node.Outpoint{Txid: spend.PrevTxid.String(), Vout: int(spend.PrevIndex)})
+	}
+	spendings, err := bc.GetTxSpendingPrevout(ctx, outpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	spendingTxids := make(map[string]Bytes, len(spendings))
+	for _, spending := range spendings {
+		if spending.SpendingTxid != nil {
+			spendingTxids[fmt.Sprintf("%s:%d", spending.Txid, spending.Vout)] = *spending.SpendingTxid
+		}
+	}
+	for _, spend := range spends {
+		spendingTxid, exists := spendingTxids[fmt.Sprintf("%s:%d", spend.PrevTxid, spend.PrevIndex)]
+		if exists && !bytes.Equal(spendingTxid, spend.Txid) {
+			replacements[spend.Txid.String()] = spendingTxid
+		}
+	}
+	return replacements, nil
+}
+
+// fetches a tx group from the nodes, then stores its dependent tx under the chain lock
+func (s *Syncer) processTxGroup(ctx context.Context, txGroup []string) error {
+	var hexes []string
+	var dependent *node.Transaction
+
+	for i, txid := range txGroup {
+		tx, err := s.bc.GetTransaction(ctx, txid)
+		if err != nil {
+			s.logger.Warn("skipping mempool tx", "txid", txid, "error", err)
+			continue
+		}
+		hexes = append(hexes, tx.Hex.String())
+
+		// Store only the last transaction (dependent one)
+		if i == len(txGroup)-1 {
+			dependent = tx
+		}
+	}
+
+	var metaTx *node.MetaTransaction
+	if len(hexes) > 0 {
+		metaTxs, err := s.sc.CheckPackage(ctx, hexes)
+		if err != nil {
+			return err
+		}
+		// the last metaTx is the dependent one's
+		if len(metaTxs) > 0 {
+			metaTx = metaTxs[len(metaTxs)-1]
+		}
+	}
+	if dependent == nil {
+		return nil
+	}
+
+	s.chainMu.Lock()
+	defer s.chainMu.Unlock()
+
+	ctx, sqlTx, err := tracing.BeginTx(ctx, s.pg, "processTxGroup")
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback(ctx)
+
+	q := db.New(sqlTx)
+	// drops a copy kept from an earlier replacement, in case the tx came back
+	if err := q.DeleteMempoolTransactionByTxid(ctx, dependent.Txid); err != nil {
+		return err
+	}
+	if err := store.StoreMempoolTransaction(ctx, q, dependent); err != nil {
+		return err
+	}
+	if err := store.StoreMempoolSpends(ctx, q, dependent); err != nil {
+		return err
+	}
+	if metaTx != nil {
+		if sqlTx, err = store.StoreMempoolSpacesTransaction(ctx, *metaTx, sqlTx); err != nil {
+			return err
+		}
+	}
+	return sqlTx.Commit(ctx)
+}
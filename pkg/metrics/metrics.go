@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "indexer"
+
+var (
+	SyncedHeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "synced_height",
+		Help:      "Height of the last block stored in the db.",
+	})
+	NodeTipHeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "node_tip_height",
+		Help:      "Height of the bitcoin node's best block.",
+	})
+	SpacedTipHeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "spaced_tip_height",
+		Help:      "Height of the spaces node's tip.",
+	})
+	SyncLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_lag_blocks",
+		Help:      "Number of blocks the db is behind the bitcoin node.",
+	})
+	BlockStoreDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "block_store_duration_seconds",
+		Help:      "Time spent storing a single block, spaces metadata included.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80},
+	})
+	RpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of the node RPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	RpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Number of failed node RPC calls.",
+	}, []string{"method"})
+	MempoolDBTxs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mempool_db_txs",
+		Help:      "Number of mempool txs stored in the db.",
+	})
+	MempoolNodeTxs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mempool_node_txs",
+		Help:      "Number of txs in the bitcoin node's mempool.",
+	})
+	FeeMarketFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fee_market_failures_total",
+		Help:      "Number of mempool passes that failed to store a fee snapshot.",
+	})
+	Reorgs = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reorgs_total",
+		Help:      "Number of chain reorganizations detected.",
+	})
+	ReorgDepth = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reorg_depth_blocks",
+		Help:      "Number of blocks orphaned by a reorganization.",
+		Buckets:   []float64{1, 2, 3, 4, 6, 10, 20, 50},
+	})
+	UnknownCovenantActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unknown_covenant_actions_total",
+		Help:      "Number of spaces actions stored with a covenant type the indexer does not know.",
+	}, []string{"type"})
+	DBConnectionFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_connection_failures_total",
+		Help:      "Number of failed attempts to connect to the db.",
+	})
+)
+
+// ObserveRpc records the latency of a node RPC call and counts it as failed when err is set
+func ObserveRpc(method string, start time.Time, err error) {
+	RpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		RpcErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// SetSyncedHeight updates the synced height along with the lag behind the bitcoin node's tip
+func SetSyncedHeight(height int32, nodeTip int32) {
+	SyncedHeight.Set(float64(height))
+	if nodeTip >= 0 {
+		SyncLag.Set(float64(nodeTip - height))
+	}
+}
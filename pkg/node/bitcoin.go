@@ -2,7 +2,8 @@ package node
 
 import (
 	"context"
-	"log"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
 
 	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
 )
@@ -13,13 +14,12 @@ type BitcoinClient struct {
 	*Client
 }
 
-func (client *BitcoinClient) GetBlockChainInfo() {
-	ctx := context.Background()
-	var x interface{}
-	// var z []string
-	err := client.Rpc(ctx, "getblockchaininfo", []interface{}{}, x)
-	log.Print(err)
-
+func (client *BitcoinClient) GetBlockChainInfo(ctx context.Context) (map[string]interface{}, error) {
+	info := make(map[string]interface{})
+	if err := client.Rpc(ctx, "getblockchaininfo", []interface{}{}, &info); err != nil {
+		return nil, err
+	}
+	return info, nil
 }
 
 func (client *BitcoinClient) GetBlock(ctx context.Context, blockHash string) (*Block, error) {
@@ -41,6 +41,15 @@ func (client *BitcoinClient) GetBlockHash(ctx context.Context, height int) (*Byt
 	return blockHash, err
 }
 
+func (client *BitcoinClient) GetBlockCount(ctx context.Context) (int32, error) {
+	var height int32
+	err := client.Rpc(ctx, "getblockcount", []interface{}{}, &height)
+	if err != nil {
+		return -1, err
+	}
+	return height, nil
+}
+
 func (client *BitcoinClient) GetBestBlockHeight(ctx context.Context) (int32, Bytes, error) {
 	blockHash, err := client.GetBestBlockHash(ctx)
 	if err != nil {
@@ -93,23 +102,64 @@ func (client *BitcoinClient) GetMempoolTxs(ctx context.Context) ([]Transaction,
 	return txs, nil
 }
 
+// returns the mempool txs spending the given outpoints, if any
+func (client *BitcoinClient) GetTxSpendingPrevout(ctx context.Context, outpoints []Outpoint) ([]SpendingPrevout, error) {
+	spendings := make([]SpendingPrevout, 0, len(outpoints))
+	err := client.Rpc(ctx, "gettxspendingprevout", []interface{}{outpoints}, &spendings)
+	if err != nil {
+		return nil, err
+	}
+	return spendings, nil
+}
+
+func (client *BitcoinClient) EstimateSmartFee(ctx context.Context, confTarget int) (*FeeEstimate, error) {
+	estimate := new(FeeEstimate)
+	err := client.Rpc(ctx, "estimatesmartfee", []interface{}{confTarget}, estimate)
+	if err != nil {
+		return nil, err
+	}
+	return estimate, err
+}
+
 type MempoolTx struct {
-	Time    int64    `json:"time"`
-	Depends []string `json:"depends"`
+	VSize             int64       `json:"vsize"`
+	Weight            int64       `json:"weight"`
+	Time              int64       `json:"time"`
+	Height            int64       `json:"height"`
+	DescendantCount   int32       `json:"descendantcount"`
+	DescendantSize    int64       `json:"descendantsize"`
+	AncestorCount     int32       `json:"ancestorcount"`
+	AncestorSize      int64       `json:"ancestorsize"`
+	Fees              MempoolFees `json:"fees"`
+	Depends           []string    `json:"depends"`
+	SpentBy           []string    `json:"spentby"`
+	Bip125Replaceable bool        `json:"bip125-replaceable"`
 }
 
-func (client *BitcoinClient) GetMempoolTxIds(ctx context.Context) ([][]string, error) {
+// fees of the mempool entry in BTC, as reported by getrawmempool
+type MempoolFees struct {
+	Base       float64 `json:"base"`
+	Modified   float64 `json:"modified"`
+	Ancestor   float64 `json:"ancestor"`
+	Descendant float64 `json:"descendant"`
+}
+
+// returns the verbose mempool of the node keyed by txid
+func (client *BitcoinClient) GetMempoolEntries(ctx context.Context) (map[string]MempoolTx, error) {
 	response := make(map[string]MempoolTx)
 	err := client.Rpc(ctx, "getrawmempool", []interface{}{true}, &response)
 	if err != nil {
 		return nil, err
 	}
+	logging.Component("node").Debug("fetched node mempool", "txs", len(response))
+	return response, nil
+}
 
-	size := len(response)
-	log.Printf("found %d txs in node's mempool", size)
-	orderedGroups := make([][]string, 0, size)
+// groups every mempool tx with the txs it depends on, the dependent tx goes last
+func GroupMempoolTxs(entries map[string]MempoolTx) [][]string {
+	orderedGroups := make([][]string, 0, len(entries))
 
-	for txid, info := range response {
+	for txid, info := range entries {
 		if len(info.Depends) == 0 {
 			orderedGroups = append(orderedGroups, []string{txid})
 		} else {
@@ -119,5 +169,5 @@ func (client *BitcoinClient) GetMempoolTxIds(ctx context.Context) ([][]string, e
 			orderedGroups = append(orderedGroups, group)
 		}
 	}
-	return orderedGroups, nil
+	return orderedGroups
 }
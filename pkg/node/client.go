@@ -7,8 +7,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Client struct {
@@ -28,7 +34,7 @@ func NewClient(origin, username, password string) *Client {
 
 	return &Client{
 		httpclient: &http.Client{
-			Transport: transport,
+			Transport: fixtureTransport(transport),
 			Timeout:   30 * time.Second, // Overall request timeout
 		},
 		origin:   origin,
@@ -37,6 +43,30 @@ func NewClient(origin, username, password string) *Client {
 	}
 }
 
+// fixtureTransport records the rpc traffic to NODE_RPC_RECORD_DIR,
+// or serves it from NODE_RPC_REPLAY_DIR without reaching the node
+func fixtureTransport(transport http.RoundTripper) http.RoundTripper {
+	if dir := os.Getenv("NODE_RPC_REPLAY_DIR"); dir != "" {
+		logging.Component("node").Warn("replaying recorded rpc responses", "dir", dir)
+		return NewReplayTransport(dir)
+	}
+	if dir := os.Getenv("NODE_RPC_RECORD_DIR"); dir != "" {
+		logging.Component("node").Info("recording rpc traffic", "dir", dir)
+		return NewRecordingTransport(dir, transport)
+	}
+	return transport
+}
+
+// SetTransport replaces the transport of the client, e.g. with a recording or replay one
+func (client *Client) SetTransport(transport http.RoundTripper) {
+	client.httpclient.Transport = transport
+}
+
+// Transport returns the transport the client sends its requests with
+func (client *Client) Transport() http.RoundTripper {
+	return client.httpclient.Transport
+}
+
 func (client *Client) do(ctx context.Context, method string, path string, body interface{}, target interface{}) error {
 	var reader io.Reader = nil
 	if body != nil {
@@ -84,6 +114,23 @@ type RpcResponse struct {
 }
 
 func (client *Client) Rpc(ctx context.Context, method string, params []interface{}, target interface{}) error {
+	ctx, span := tracing.Start(ctx, "rpc "+method,
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", method),
+		attribute.String("server.address", client.origin),
+	)
+	start := time.Now()
+	err := client.rpc(ctx, method, params, target)
+	tracing.End(span, err)
+	metrics.ObserveRpc(method, start, err)
+	logging.Component("node").Debug("rpc call", "method", method, "duration", time.Since(start), "error", err)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+func (client *Client) rpc(ctx context.Context, method string, params []interface{}, target interface{}) error {
 	body := rpcBody{method, params, "2.0", 1337}
 	response := RpcResponse{}
 	if err := client.do(ctx, "POST", "", &body, &response); err != nil {
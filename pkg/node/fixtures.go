@@ -0,0 +1,171 @@
+package node
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// a recorded rpc call, one file per call in the fixture directory
+type Fixture struct {
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Response json.RawMessage `json:"response"`
+}
+
+// RecordingTransport passes the rpc calls to Next and writes every request and response to Dir.
+// Recording again into a directory keeps its fixtures, the new ones are numbered after them.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func NewRecordingTransport(dir string, next http.RoundTripper) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RecordingTransport{Dir: dir, Next: next, calls: make(map[string]int)}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, call, err := readRpcBody(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	response, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(response))
+
+	t.mu.Lock()
+	key := fixtureKey(call.Method, body)
+	seq, seen := t.calls[key]
+	if !seen {
+		seq = lastFixture(t.Dir, key) + 1
+	}
+	t.calls[key] = seq + 1
+	t.mu.Unlock()
+
+	fixture := Fixture{Method: call.Method, Params: call.Params, Response: response}
+	buf, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fixturePath(t.Dir, key, seq), buf, 0o644); err != nil {
+		return nil, fmt.Errorf("recording %s: %w", call.Method, err)
+	}
+	return res, nil
+}
+
+// ReplayTransport answers the rpc calls with the fixtures recorded in Dir. Identical calls get
+// the responses in the order they were recorded, the last one repeats once they run out.
+type ReplayTransport struct {
+	Dir string
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{Dir: dir, calls: make(map[string]int)}
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, call, err := readRpcBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	key := fixtureKey(call.Method, body)
+	seq := t.calls[key]
+	t.calls[key]++
+	t.mu.Unlock()
+
+	buf, err := os.ReadFile(fixturePath(t.Dir, key, seq))
+	if os.IsNotExist(err) && seq > 0 {
+		// out of recorded responses, repeat the last one
+		seq = lastFixture(t.Dir, key)
+		buf, err = os.ReadFile(fixturePath(t.Dir, key, seq))
+	}
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no fixture recorded for %s %s", call.Method, call.Params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(buf, &fixture); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", fixturePath(t.Dir, key, seq), err)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(fixture.Response)),
+		ContentLength: int64(len(fixture.Response)),
+		Request:       req,
+	}, nil
+}
+
+type fixtureCall struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// reads the rpc body of a request and puts it back for the next transport
+func readRpcBody(req *http.Request) ([]byte, fixtureCall, error) {
+	var call fixtureCall
+	if req.Body == nil {
+		return nil, call, fmt.Errorf("rpc request without body")
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, call, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err := json.Unmarshal(body, &call); err != nil {
+		return nil, call, err
+	}
+	return body, call, nil
+}
+
+// calls with the same method and params share a key, the request id is always the same
+func fixtureKey(method string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return method + "-" + hex.EncodeToString(sum[:8])
+}
+
+func fixturePath(dir string, key string, seq int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%04d.json", key, seq))
+}
+
+// lastFixture returns the sequence of the last fixture recorded for key, -1 if there is none
+func lastFixture(dir string, key string) int {
+	matches, _ := filepath.Glob(filepath.Join(dir, key+"-*.json"))
+	return len(matches) - 1
+}
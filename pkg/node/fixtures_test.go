@@ -0,0 +1,156 @@
+package node_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node/nodetest"
+)
+
+// record runs the same calls against a fresh fake bitcoind, recording them to dir
+func record(t *testing.T, dir string) {
+	t.Helper()
+	ctx := context.Background()
+	chain := nodetest.NewChain()
+	chain.MineN(2)
+	server := nodetest.NewBitcoind(chain)
+	defer server.Close()
+
+	client := server.Client()
+	client.SetTransport(node.NewRecordingTransport(dir, nil))
+	bc := &node.BitcoinClient{Client: client}
+
+	if _, err := bc.GetBlockCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.GetBlockHash(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine()
+	if _, err := bc.GetBlockCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFixtures(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		buf, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixtures[entry.Name()] = buf
+	}
+	return fixtures
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	chain := nodetest.NewChain()
+	chain.MineN(2)
+	server := nodetest.NewBitcoind(chain)
+
+	client := server.Client()
+	client.SetTransport(node.NewRecordingTransport(dir, nil))
+	bc := &node.BitcoinClient{Client: client}
+
+	hash, err := bc.GetBlockHash(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstCount, err := bc.GetBlockCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine()
+	secondCount, err := bc.GetBlockCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	replay := server.Client()
+	replay.SetTransport(node.NewReplayTransport(dir))
+	rbc := &node.BitcoinClient{Client: replay}
+
+	t.Run("answers without the server", func(t *testing.T) {
+		got, err := rbc.GetBlockHash(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(*got, *hash) {
+			t.Errorf("getblockhash = %s, want %s", got, hash)
+		}
+	})
+	t.Run("repeated identical calls in recorded order", func(t *testing.T) {
+		for i, want := range []int32{firstCount, secondCount} {
+			got, err := rbc.GetBlockCount(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("call %d: getblockcount = %d, want %d", i, got, want)
+			}
+		}
+	})
+	t.Run("out of recorded calls repeats the last one", func(t *testing.T) {
+		got, err := rbc.GetBlockCount(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != secondCount {
+			t.Errorf("getblockcount = %d, want %d", got, secondCount)
+		}
+	})
+	t.Run("unrecorded call fails", func(t *testing.T) {
+		_, err := rbc.GetBlockHash(ctx, 2)
+		if err == nil || !strings.Contains(err.Error(), "no fixture recorded") {
+			t.Errorf("err = %v, want no fixture recorded", err)
+		}
+	})
+}
+
+func TestRecordingIsDeterministic(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	record(t, first)
+	record(t, second)
+
+	want := readFixtures(t, first)
+	got := readFixtures(t, second)
+	if len(got) != len(want) {
+		t.Fatalf("recorded %d fixtures, then %d", len(want), len(got))
+	}
+	for name, buf := range want {
+		if !bytes.Equal(got[name], buf) {
+			t.Errorf("fixture %s differs between recordings", name)
+		}
+	}
+}
+
+func TestRecordingContinuesNumbering(t *testing.T) {
+	dir := t.TempDir()
+	record(t, dir)
+	before := readFixtures(t, dir)
+	record(t, dir)
+	after := readFixtures(t, dir)
+
+	if len(after) != 2*len(before) {
+		t.Fatalf("recording twice left %d fixtures, want %d", len(after), 2*len(before))
+	}
+	for name, buf := range before {
+		if !bytes.Equal(after[name], buf) {
+			t.Errorf("fixture %s was overwritten", name)
+		}
+	}
+}
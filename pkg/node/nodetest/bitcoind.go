@@ -0,0 +1,166 @@
+package nodetest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// NewBitcoind returns a fake bitcoind serving chain
+func NewBitcoind(chain *Chain) *Server {
+	s := NewServer()
+	s.Handle("getblockchaininfo", chain.getBlockChainInfo)
+	s.Handle("getblockcount", chain.getBlockCount)
+	s.Handle("getblockhash", chain.getBlockHash)
+	s.Handle("getbestblockhash", chain.getBestBlockHash)
+	s.Handle("getblock", chain.getBlock)
+	s.Handle("getrawtransaction", chain.getRawTransaction)
+	s.Handle("getrawmempool", chain.getRawMempool)
+	s.Handle("gettxspendingprevout", chain.getTxSpendingPrevout)
+	s.Handle("estimatesmartfee", chain.estimateSmartFee)
+	return s
+}
+
+// NewBitcoinClient returns a bitcoin client talking to s
+func NewBitcoinClient(s *Server) *node.BitcoinClient {
+	return &node.BitcoinClient{Client: s.Client()}
+}
+
+func (c *Chain) getBlockChainInfo(params []json.RawMessage) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tip := c.active[len(c.active)-1]
+	return map[string]interface{}{
+		"chain":         "regtest",
+		"blocks":        tip.Height,
+		"headers":       tip.Height,
+		"bestblockhash": tip.Hash,
+		"mediantime":    tip.MedianTime,
+		"chainwork":     tip.Chainwork,
+	}, nil
+}
+
+func (c *Chain) getBlockCount(params []json.RawMessage) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.active) - 1, nil
+}
+
+func (c *Chain) getBlockHash(params []json.RawMessage) (interface{}, error) {
+	var height int
+	if err := param(params, 0, &height); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height < 0 || height >= len(c.active) {
+		return nil, &RpcError{Code: ErrCodeInvalidParameter, Message: "Block height out of range"}
+	}
+	return c.active[height].Hash, nil
+}
+
+func (c *Chain) getBestBlockHash(params []json.RawMessage) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active[len(c.active)-1].Hash, nil
+}
+
+// the block is always returned with its transactions decoded, as with verbosity 2
+func (c *Chain) getBlock(params []json.RawMessage) (interface{}, error) {
+	var hash Bytes
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, exists := c.blocks[hash.String()]
+	if !exists {
+		return nil, &RpcError{Code: ErrCodeInvalidAddressOrKey, Message: "Block not found"}
+	}
+	return c.view(block), nil
+}
+
+func (c *Chain) getRawTransaction(params []json.RawMessage) (interface{}, error) {
+	var txid string
+	if err := param(params, 0, &txid); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, _ := c.findTx(txid)
+	if tx == nil {
+		return nil, &RpcError{Code: ErrCodeInvalidAddressOrKey, Message: "No such mempool or blockchain transaction"}
+	}
+	return tx, nil
+}
+
+func (c *Chain) getRawMempool(params []json.RawMessage) (interface{}, error) {
+	var verbose bool
+	if err := param(params, 0, &verbose); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !verbose {
+		txids := make([]string, 0, len(c.mempool))
+		for txid := range c.mempool {
+			txids = append(txids, txid)
+		}
+		return txids, nil
+	}
+
+	entries := make(map[string]node.MempoolTx, len(c.mempool))
+	for txid, mtx := range c.mempool {
+		entry := mtx.entry
+		for otherTxid, other := range c.mempool {
+			for _, depend := range other.entry.Depends {
+				if depend == txid {
+					entry.SpentBy = append(entry.SpentBy, otherTxid)
+				}
+			}
+		}
+		entries[txid] = entry
+	}
+	return entries, nil
+}
+
+func (c *Chain) getTxSpendingPrevout(params []json.RawMessage) (interface{}, error) {
+	var outpoints []node.Outpoint
+	if err := param(params, 0, &outpoints); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spendings := make([]node.SpendingPrevout, 0, len(outpoints))
+	for _, outpoint := range outpoints {
+		var txid Bytes
+		if err := txid.UnmarshalString(outpoint.Txid); err != nil {
+			return nil, &RpcError{Code: ErrCodeInvalidParameter, Message: fmt.Sprintf("txid must be hexadecimal string (not '%s')", outpoint.Txid)}
+		}
+		spending := node.SpendingPrevout{Txid: txid, Vout: outpoint.Vout}
+		for _, mtx := range c.mempool {
+			for _, vin := range mtx.tx.Vin {
+				if vin.HashPrevout != nil && vin.HashPrevout.String() == outpoint.Txid && vin.IndexPrevout == outpoint.Vout {
+					spending.SpendingTxid = bytesPtr(mtx.tx.Txid)
+				}
+			}
+		}
+		spendings = append(spendings, spending)
+	}
+	return spendings, nil
+}
+
+func (c *Chain) estimateSmartFee(params []json.RawMessage) (interface{}, error) {
+	var target int
+	if err := param(params, 0, &target); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.feeRate == 0 {
+		return node.FeeEstimate{Errors: []string{"Insufficient data or no feerate found"}, Blocks: target}, nil
+	}
+	return node.FeeEstimate{FeeRate: c.feeRate, Blocks: target}, nil
+}
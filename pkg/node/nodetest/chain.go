@@ -0,0 +1,368 @@
+package nodetest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// time of the genesis block, every following block is 10 minutes later
+const genesisTime = 1700000000
+
+// Chain is a scripted block chain shared by the fake bitcoind and spaced servers.
+// Blocks disconnected by a reorg stay retrievable by hash, like they do in bitcoind.
+type Chain struct {
+	mu sync.Mutex
+
+	active      []*node.Block
+	blocks      map[string]*node.Block
+	meta        map[string][]node.MetaTransaction
+	mempool     map[string]*mempoolTx
+	rollouts    map[int][]node.RollOutSpace
+	rootAnchors []*node.RootAnchor
+
+	// number of blocks spaced is behind the bitcoin tip
+	spacedLag int
+	// fee rate returned by estimatesmartfee in BTC/kvB, 0 means not enough data
+	feeRate float64
+	// changes the hashes of blocks mined after a reorg
+	nonce   uint64
+	txCount uint64
+}
+
+type mempoolTx struct {
+	tx    node.Transaction
+	entry node.MempoolTx
+	meta  *node.MetaTransaction
+}
+
+// NewChain returns a chain holding only the genesis block
+func NewChain() *Chain {
+	c := &Chain{
+		blocks:   make(map[string]*node.Block),
+		meta:     make(map[string][]node.MetaTransaction),
+		mempool:  make(map[string]*mempoolTx),
+		rollouts: make(map[int][]node.RollOutSpace),
+	}
+	c.Mine()
+	return c
+}
+
+// Height returns the height of the tip
+func (c *Chain) Height() int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int32(len(c.active) - 1)
+}
+
+// Tip returns the last block of the active chain
+func (c *Chain) Tip() *node.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.view(c.active[len(c.active)-1])
+}
+
+// BlockAt returns the active block at height, nil if the chain is shorter
+func (c *Chain) BlockAt(height int32) *node.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height < 0 || int(height) >= len(c.active) {
+		return nil
+	}
+	return c.view(c.active[height])
+}
+
+// Block returns any block ever mined, including the disconnected ones
+func (c *Chain) Block(hash Bytes) *node.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, exists := c.blocks[hash.String()]
+	if !exists {
+		return nil
+	}
+	return c.view(block)
+}
+
+// SpacesBlock returns the spaces meta of any block ever mined, as getblockmeta serves it
+func (c *Chain) SpacesBlock(hash Bytes) *node.SpacesBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, exists := c.blocks[hash.String()]
+	if !exists {
+		return nil
+	}
+	return &node.SpacesBlock{
+		Transactions: append([]node.MetaTransaction(nil), c.meta[hash.String()]...),
+		Height:       int(block.Height),
+		Hash:         block.Hash,
+	}
+}
+
+// Mine appends a block holding a coinbase and txs, the txs leave the mempool
+func (c *Chain) Mine(txs ...node.Transaction) *node.Block {
+	return c.MineMeta(nil, txs...)
+}
+
+// MineMeta appends a block whose spaces transactions are metas,
+// a meta without txid belongs to the tx at the same position
+func (c *Chain) MineMeta(metas []node.MetaTransaction, txs ...node.Transaction) *node.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.view(c.mine(metas, txs))
+}
+
+// MineN appends n empty blocks and returns the new tip
+func (c *Chain) MineN(n int) *node.Block {
+	var block *node.Block
+	for i := 0; i < n; i++ {
+		block = c.Mine()
+	}
+	return block
+}
+
+// MineMempool appends a block with every mempool tx and their spaces metas
+func (c *Chain) MineMempool() *node.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	txids := make([]string, 0, len(c.mempool))
+	for txid := range c.mempool {
+		txids = append(txids, txid)
+	}
+	sort.Strings(txids)
+
+	var txs []node.Transaction
+	var metas []node.MetaTransaction
+	for _, txid := range txids {
+		mtx := c.mempool[txid]
+		txs = append(txs, mtx.tx)
+		if mtx.meta != nil {
+			metas = append(metas, *mtx.meta)
+		}
+	}
+	return c.view(c.mine(metas, txs))
+}
+
+func (c *Chain) mine(metas []node.MetaTransaction, txs []node.Transaction) *node.Block {
+	height := int32(len(c.active))
+	var prev Bytes
+	if height > 0 {
+		prev = c.active[height-1].Hash
+	}
+
+	coinbase := node.Transaction{
+		Version: 2,
+		Size:    100,
+		VSize:   100,
+		Weight:  400,
+		Vin:     []node.Vin{{Coinbase: bytesPtr(heightBytes(height)), Sequence: 0xffffffff}},
+		Vout:    []node.Vout{{FloatValue: 50, Index: 0}},
+	}
+	c.fillTx(&coinbase)
+
+	block := &node.Block{
+		Hash:          hash("block", prev, heightBytes(height), nonceBytes(c.nonce)),
+		Size:          1000,
+		StrippedSize:  1000,
+		Weight:        4000,
+		Height:        height,
+		Version:       0x20000000,
+		Transactions:  append([]node.Transaction{coinbase}, txs...),
+		Time:          int32(genesisTime + 600*height),
+		MedianTime:    int32(genesisTime + 600*height),
+		Nonce:         int64(height),
+		Bits:          Bytes{0x20, 0x7f, 0xff, 0xff},
+		Difficulty:    1,
+		Chainwork:     hash("chainwork", heightBytes(height)),
+		PrevBlockHash: prev,
+	}
+	var txids []Bytes
+	for _, tx := range block.Transactions {
+		txids = append(txids, tx.Txid)
+		delete(c.mempool, tx.Txid.String())
+	}
+	block.HashMerkleRoot = hash("merkle", txids...)
+
+	for i := range metas {
+		if metas[i].TxID == nil && i+1 < len(block.Transactions) {
+			metas[i].TxID = block.Transactions[i+1].Txid
+		}
+	}
+	if metas == nil {
+		metas = []node.MetaTransaction{}
+	}
+
+	c.active = append(c.active, block)
+	c.blocks[block.Hash.String()] = block
+	c.meta[block.Hash.String()] = metas
+	return block
+}
+
+// Reorg disconnects the top depth blocks, the blocks mined next get different hashes
+func (c *Chain) Reorg(depth int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if depth >= len(c.active) {
+		depth = len(c.active) - 1
+	}
+	c.active = c.active[:len(c.active)-depth]
+	c.nonce++
+}
+
+// NewTx returns a tx spending the given outpoints into one output, with a unique txid
+func (c *Chain) NewTx(spends ...node.Outpoint) node.Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx := node.Transaction{
+		Version:  2,
+		Size:     200,
+		VSize:    150,
+		Weight:   600,
+		Vout:     []node.Vout{{FloatValue: 0.001, Index: 0}},
+		FloatFee: 0.00001,
+	}
+	for _, spend := range spends {
+		var txid Bytes
+		if err := txid.UnmarshalString(spend.Txid); err != nil {
+			panic(fmt.Sprintf("nodetest: invalid outpoint txid %q", spend.Txid))
+		}
+		tx.Vin = append(tx.Vin, node.Vin{HashPrevout: &txid, IndexPrevout: spend.Vout, Sequence: 0xfffffffd})
+	}
+	c.fillTx(&tx)
+	return tx
+}
+
+// fillTx gives a tx without txid a unique one and a raw hex
+func (c *Chain) fillTx(tx *node.Transaction) {
+	c.txCount++
+	if tx.Txid == nil {
+		tx.Txid = hash("tx", nonceBytes(c.txCount))
+	}
+	if tx.Hash == nil {
+		tx.Hash = tx.Txid
+	}
+	if tx.Hex == nil {
+		tx.Hex = append(Bytes{0x02, 0x00, 0x00, 0x00}, tx.Txid...)
+	}
+}
+
+// AddMempoolTx adds a tx and its spaces meta, if any, to the mempool and returns it
+// with its txid filled in. depends lists the mempool txs it spends from.
+func (c *Chain) AddMempoolTx(tx node.Transaction, meta *node.MetaTransaction, depends ...Bytes) node.Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fillTx(&tx)
+	if meta != nil && meta.TxID == nil {
+		meta.TxID = tx.Txid
+	}
+	entry := node.MempoolTx{
+		VSize:         int64(tx.VSize),
+		Weight:        int64(tx.Weight),
+		Time:          genesisTime + 600*int64(len(c.active)),
+		Height:        int64(len(c.active) - 1),
+		AncestorCount: 1 + int32(len(depends)),
+		AncestorSize:  int64(tx.VSize),
+		Fees: node.MempoolFees{
+			Base:     tx.FloatFee,
+			Modified: tx.FloatFee,
+			Ancestor: tx.FloatFee,
+		},
+		Bip125Replaceable: true,
+	}
+	for _, depend := range depends {
+		entry.Depends = append(entry.Depends, depend.String())
+	}
+	c.mempool[tx.Txid.String()] = &mempoolTx{tx: tx, entry: entry, meta: meta}
+	return tx
+}
+
+// RemoveMempoolTx drops a tx from the mempool, like an eviction would
+func (c *Chain) RemoveMempoolTx(txid Bytes) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.mempool, txid.String())
+}
+
+// SetRollout sets the spaces returned by getrollout for a page
+func (c *Chain) SetRollout(page int, spaces []node.RollOutSpace) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollouts[page] = spaces
+}
+
+// AddRootAnchor anchors root at the current tip
+func (c *Chain) AddRootAnchor(root Bytes) *node.RootAnchor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tip := c.active[len(c.active)-1]
+	anchor := &node.RootAnchor{Root: root, Block: node.BlockInfo{Hash: tip.Hash, Height: int(tip.Height)}}
+	c.rootAnchors = append(c.rootAnchors, anchor)
+	return anchor
+}
+
+// SetSpacedLag makes spaced report a tip that many blocks behind bitcoind
+func (c *Chain) SetSpacedLag(blocks int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spacedLag = blocks
+}
+
+// SetFeeRate sets the fee rate estimatesmartfee returns, in BTC/kvB
+func (c *Chain) SetFeeRate(feeRate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.feeRate = feeRate
+}
+
+// view returns a copy of a block with its position in the active chain filled in
+func (c *Chain) view(block *node.Block) *node.Block {
+	copied := *block
+	copied.NextBlockHash = nil
+	if c.isActive(block) && int(block.Height)+1 < len(c.active) {
+		copied.NextBlockHash = c.active[block.Height+1].Hash
+	}
+	return &copied
+}
+
+func (c *Chain) isActive(block *node.Block) bool {
+	return int(block.Height) < len(c.active) && c.active[block.Height] == block
+}
+
+func (c *Chain) findTx(txid string) (*node.Transaction, *node.Block) {
+	if mtx, exists := c.mempool[txid]; exists {
+		return &mtx.tx, nil
+	}
+	for i := len(c.active) - 1; i >= 0; i-- {
+		for j := range c.active[i].Transactions {
+			if c.active[i].Transactions[j].Txid.String() == txid {
+				return &c.active[i].Transactions[j], c.active[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func hash(kind string, parts ...Bytes) Bytes {
+	h := sha256.New()
+	h.Write([]byte(kind))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func heightBytes(height int32) Bytes {
+	return binary.LittleEndian.AppendUint32(nil, uint32(height))
+}
+
+func nonceBytes(nonce uint64) Bytes {
+	return binary.LittleEndian.AppendUint64(nil, nonce)
+}
+
+func bytesPtr(b Bytes) *Bytes {
+	return &b
+}
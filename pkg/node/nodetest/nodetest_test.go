@@ -0,0 +1,164 @@
+package nodetest_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node/nodetest"
+)
+
+func newClients(t *testing.T, chain *nodetest.Chain) (*node.BitcoinClient, *node.SpacesClient) {
+	t.Helper()
+	bitcoind := nodetest.NewBitcoind(chain)
+	t.Cleanup(bitcoind.Close)
+	spaced := nodetest.NewSpaced(chain)
+	t.Cleanup(spaced.Close)
+	return nodetest.NewBitcoinClient(bitcoind), nodetest.NewSpacesClient(spaced)
+}
+
+func TestGetBlock(t *testing.T) {
+	ctx := context.Background()
+	chain := nodetest.NewChain()
+	tx := chain.NewTx()
+	mined := chain.Mine(tx)
+	chain.Mine()
+	bc, _ := newClients(t, chain)
+
+	hash, err := bc.GetBlockHash(ctx, int(mined.Height))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(*hash, mined.Hash) {
+		t.Fatalf("getblockhash = %s, want %s", hash, mined.Hash)
+	}
+
+	block, err := bc.GetBlock(ctx, hash.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Height != mined.Height {
+		t.Errorf("height = %d, want %d", block.Height, mined.Height)
+	}
+	if !bytes.Equal(block.PrevBlockHash, chain.BlockAt(mined.Height-1).Hash) {
+		t.Errorf("previous block hash = %s, want the block below", block.PrevBlockHash)
+	}
+	if !bytes.Equal(block.NextBlockHash, chain.Tip().Hash) {
+		t.Errorf("next block hash = %s, want the tip %s", block.NextBlockHash, chain.Tip().Hash)
+	}
+	if len(block.Transactions) != 2 {
+		t.Fatalf("block has %d txs, want the coinbase and one tx", len(block.Transactions))
+	}
+	if !bytes.Equal(block.Transactions[1].Txid, tx.Txid) {
+		t.Errorf("tx 1 = %s, want %s", block.Transactions[1].Txid, tx.Txid)
+	}
+
+	if _, err := bc.GetBlock(ctx, strings.Repeat("00", 32)); err == nil {
+		t.Error("getblock of an unknown hash returned no error")
+	}
+}
+
+func TestGetBlockHashOutOfRange(t *testing.T) {
+	ctx := context.Background()
+	chain := nodetest.NewChain()
+	chain.MineN(3)
+	bc, _ := newClients(t, chain)
+
+	for _, height := range []int{-1, 4, 100} {
+		_, err := bc.GetBlockHash(ctx, height)
+		if err == nil || !strings.Contains(err.Error(), "Block height out of range") {
+			t.Errorf("getblockhash %d: err = %v, want out of range", height, err)
+		}
+	}
+	if _, err := bc.GetBlockHash(ctx, 3); err != nil {
+		t.Errorf("getblockhash of the tip: %v", err)
+	}
+}
+
+func TestReorg(t *testing.T) {
+	ctx := context.Background()
+	chain := nodetest.NewChain()
+	chain.MineN(5)
+	orphaned := chain.Tip()
+	bc, sc := newClients(t, chain)
+
+	chain.Reorg(2)
+	chain.MineN(3)
+
+	height, tipHash, err := bc.GetBestBlockHeight(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 6 {
+		t.Errorf("tip height = %d, want 6", height)
+	}
+
+	hash, err := bc.GetBlockHash(ctx, int(orphaned.Height))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(*hash, orphaned.Hash) {
+		t.Errorf("height %d still has the disconnected block", orphaned.Height)
+	}
+
+	// the disconnected block stays retrievable but is not linked to a next block anymore
+	block, err := bc.GetBlock(ctx, orphaned.Hash.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.NextBlockHash != nil {
+		t.Errorf("disconnected block has next block %s", block.NextBlockHash)
+	}
+	meta, err := sc.GetBlockMeta(ctx, orphaned.Hash.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(meta.Hash, orphaned.Hash) {
+		t.Errorf("getblockmeta hash = %s, want %s", meta.Hash, orphaned.Hash)
+	}
+
+	info, err := sc.GetServerInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(info.Tip.Hash, tipHash) {
+		t.Errorf("spaced tip = %s, want %s", info.Tip.Hash, tipHash)
+	}
+}
+
+func TestGetRawMempool(t *testing.T) {
+	ctx := context.Background()
+	chain := nodetest.NewChain()
+	funding := chain.NewTx()
+	chain.Mine(funding)
+	parent := chain.AddMempoolTx(chain.NewTx(node.Outpoint{Txid: funding.Txid.String(), Vout: 0}), nil)
+	child := chain.AddMempoolTx(chain.NewTx(node.Outpoint{Txid: parent.Txid.String(), Vout: 0}), nil, parent.Txid)
+	bc, _ := newClients(t, chain)
+
+	entries, err := bc.GetMempoolEntries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("mempool has %d txs, want 2", len(entries))
+	}
+	childEntry := entries[child.Txid.String()]
+	if len(childEntry.Depends) != 1 || childEntry.Depends[0] != parent.Txid.String() {
+		t.Errorf("child depends = %v, want [%s]", childEntry.Depends, parent.Txid)
+	}
+	parentEntry := entries[parent.Txid.String()]
+	if len(parentEntry.SpentBy) != 1 || parentEntry.SpentBy[0] != child.Txid.String() {
+		t.Errorf("parent spent by = %v, want [%s]", parentEntry.SpentBy, child.Txid)
+	}
+
+	chain.MineMempool()
+	entries, err = bc.GetMempoolEntries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("mempool has %d txs after mining it, want 0", len(entries))
+	}
+}
@@ -0,0 +1,164 @@
+// Package nodetest provides in-process fake bitcoind and spaced JSON-RPC servers
+// serving a scripted Chain, so the sync logic can run without the docker regtest stack.
+package nodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+)
+
+// error codes returned by bitcoind
+const (
+	ErrCodeInvalidParameter    = -8
+	ErrCodeInvalidAddressOrKey = -5
+	ErrCodeMethodNotFound      = -32601
+)
+
+// RpcError is returned by a handler to answer with a JSON-RPC error
+type RpcError struct {
+	Code    int
+	Message string
+}
+
+func (e *RpcError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// Handler answers a JSON-RPC call, the result is encoded as JSON
+type Handler func(params []json.RawMessage) (interface{}, error)
+
+type Call struct {
+	Method string
+	Params []json.RawMessage
+}
+
+// Server is a JSON-RPC server answering with the registered handlers
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	failures map[string]error
+	calls    []Call
+}
+
+func NewServer() *Server {
+	s := &Server{
+		handlers: make(map[string]Handler),
+		failures: make(map[string]error),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Handle registers the handler of a method, replacing the previous one
+func (s *Server) Handle(method string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+// Fail makes every call of a method return err until it is called again with a nil err
+func (s *Server) Fail(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, method)
+		return
+	}
+	s.failures[method] = err
+}
+
+// Calls returns the calls received so far, in order
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallCount returns how many times a method was called
+func (s *Server) CallCount(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, call := range s.calls {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// Client returns a node client talking to the server
+func (s *Server) Client() *node.Client {
+	return node.NewClient(s.URL, "test", "test")
+}
+
+type rpcRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     int               `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Result  interface{} `json:"result"`
+	Error   *rpcError   `json:"error"`
+	ID      int         `json:"id"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Method: req.Method, Params: req.Params})
+	handler, exists := s.handlers[req.Method]
+	failure := s.failures[req.Method]
+	s.mu.Unlock()
+
+	response := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	var err error
+	switch {
+	case failure != nil:
+		err = failure
+	case !exists:
+		err = &RpcError{Code: ErrCodeMethodNotFound, Message: "Method not found"}
+	default:
+		response.Result, err = handler(req.Params)
+	}
+	if err != nil {
+		response.Result = nil
+		response.Error = &rpcError{Code: -1, Message: err.Error()}
+		if rpcErr, ok := err.(*RpcError); ok {
+			response.Error = &rpcError{Code: rpcErr.Code, Message: rpcErr.Message}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// param decodes the i-th param into target, a missing param leaves target as is
+func param(params []json.RawMessage, i int, target interface{}) error {
+	if i >= len(params) {
+		return nil
+	}
+	if err := json.Unmarshal(params[i], target); err != nil {
+		return &RpcError{Code: ErrCodeInvalidParameter, Message: fmt.Sprintf("invalid param %d: %v", i, err)}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package nodetest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// NewSpaced returns a fake spaced serving the spaces data of chain
+func NewSpaced(chain *Chain) *Server {
+	s := NewServer()
+	s.Handle("getserverinfo", chain.getServerInfo)
+	s.Handle("getblockmeta", chain.getBlockMeta)
+	s.Handle("gettxmeta", chain.getTxMeta)
+	s.Handle("checkpackage", chain.checkPackage)
+	s.Handle("getrollout", chain.getRollout)
+	s.Handle("getrootanchors", chain.getRootAnchors)
+	return s
+}
+
+// NewSpacesClient returns a spaces client talking to s
+func NewSpacesClient(s *Server) *node.SpacesClient {
+	return &node.SpacesClient{Client: s.Client()}
+}
+
+// spacedTip returns the last block spaced has indexed
+func (c *Chain) spacedTip() *node.Block {
+	height := len(c.active) - 1 - c.spacedLag
+	if height < 0 {
+		height = 0
+	}
+	return c.active[height]
+}
+
+func (c *Chain) getServerInfo(params []json.RawMessage) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tip := c.spacedTip()
+	return node.ServerInfo{
+		Network:  "regtest",
+		Tip:      node.Tip{Hash: tip.Hash, Height: int(tip.Height)},
+		Chain:    node.ChainInfo{Blocks: int(tip.Height), Headers: len(c.active) - 1},
+		Ready:    true,
+		Progress: 1,
+	}, nil
+}
+
+func (c *Chain) getBlockMeta(params []json.RawMessage) (interface{}, error) {
+	var hash Bytes
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, exists := c.blocks[hash.String()]
+	if !exists || (c.isActive(block) && block.Height > c.spacedTip().Height) {
+		return nil, &RpcError{Code: -1, Message: fmt.Sprintf("could not find block meta for %s", hash)}
+	}
+	return node.SpacesBlock{
+		Transactions: c.meta[hash.String()],
+		Height:       int(block.Height),
+		Hash:         block.Hash,
+	}, nil
+}
+
+func (c *Chain) getTxMeta(params []json.RawMessage) (interface{}, error) {
+	var txid string
+	if err := param(params, 0, &txid); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if mtx, exists := c.mempool[txid]; exists {
+		return mtx.meta, nil
+	}
+	for i := len(c.active) - 1; i >= 0; i-- {
+		for _, meta := range c.meta[c.active[i].Hash.String()] {
+			if meta.TxID.String() == txid {
+				return meta, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkPackage returns the metas of the mempool txs with the given raw hexes, null for the others
+func (c *Chain) checkPackage(params []json.RawMessage) (interface{}, error) {
+	var hexes []string
+	if err := param(params, 0, &hexes); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metas := make([]*node.MetaTransaction, len(hexes))
+	for i, hex := range hexes {
+		for _, mtx := range c.mempool {
+			if mtx.tx.Hex.String() == hex {
+				metas[i] = mtx.meta
+			}
+		}
+	}
+	return metas, nil
+}
+
+func (c *Chain) getRollout(params []json.RawMessage) (interface{}, error) {
+	var page int
+	if err := param(params, 0, &page); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spaces := c.rollouts[page]
+	if spaces == nil {
+		spaces = []node.RollOutSpace{}
+	}
+	return spaces, nil
+}
+
+func (c *Chain) getRootAnchors(params []json.RawMessage) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	anchors := make([]*node.RootAnchor, 0, len(c.rootAnchors))
+	for _, anchor := range c.rootAnchors {
+		block, exists := c.blocks[anchor.Block.Hash.String()]
+		if exists && c.isActive(block) {
+			anchors = append(anchors, anchor)
+		}
+	}
+	return anchors, nil
+}
@@ -2,7 +2,6 @@ package node
 
 import (
 	"context"
-	"log"
 )
 
 type SpacesClient struct {
@@ -22,7 +21,6 @@ func (client *SpacesClient) GetRollOut(ctx context.Context, number int) (*[]Roll
 	var rollout []RollOutSpace
 	err := client.Rpc(ctx, "getrollout", []interface{}{number}, &rollout)
 	if err != nil {
-		log.Print(err)
 		return nil, err
 	}
 	return &rollout, err
@@ -32,7 +30,6 @@ func (client *SpacesClient) GetBlockMeta(ctx context.Context, blockHash interfac
 	txs := new(SpacesBlock)
 	err := client.Rpc(ctx, "getblockmeta", []interface{}{blockHash}, txs)
 	if err != nil {
-		log.Print(err)
 		return nil, err
 	}
 	return txs, err
@@ -42,7 +39,6 @@ func (client *SpacesClient) GetTxMeta(ctx context.Context, txId string) (*MetaTr
 	metaTx := new(MetaTransaction)
 	err := client.Rpc(ctx, "gettxmeta", []interface{}{txId}, metaTx)
 	if err != nil {
-		log.Print(err)
 		return nil, err
 	}
 	return metaTx, err
@@ -52,7 +48,6 @@ func (client *SpacesClient) CheckPackage(ctx context.Context, txHexes []string)
 	metaTxs := make([]*MetaTransaction, 0)
 	err := client.Rpc(ctx, "checkpackage", []interface{}{txHexes}, &metaTxs)
 	if err != nil {
-		log.Print(err)
 		return nil, err
 	}
 	return metaTxs, err
@@ -99,6 +99,28 @@ type ScriptPubKey struct {
 	Type    string `json:"type"`
 }
 
+type Outpoint struct {
+	Txid string `json:"txid"`
+	Vout int    `json:"vout"`
+}
+
+type SpendingPrevout struct {
+	Txid         Bytes  `json:"txid"`
+	Vout         int    `json:"vout"`
+	SpendingTxid *Bytes `json:"spendingtxid,omitempty"`
+}
+
+type FeeEstimate struct {
+	FeeRate float64  `json:"feerate,omitempty"` // BTC/kvB
+	Errors  []string `json:"errors,omitempty"`
+	Blocks  int      `json:"blocks"`
+}
+
+// returns the estimated fee rate in sat/vB
+func (estimate *FeeEstimate) SatsPerVByte() float64 {
+	return estimate.FeeRate * 1e8 / 1000
+}
+
 // Spaces types
 type Tip struct {
 	Hash   Bytes `json:"hash"`
@@ -0,0 +1,230 @@
+package pending
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jinzhu/copier"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+type Conflict string
+
+const (
+	// two or more mempool txs bid on the same space
+	ConflictCompetingBids Conflict = "competing_bids"
+	// two or more mempool txs transfer the same space
+	ConflictCompetingTransfers Conflict = "competing_transfers"
+	// a transfer is pending while the space is being bid on
+	ConflictTransferBidRace Conflict = "transfer_bid_race"
+	// a revoke is pending next to other actions on the space
+	ConflictRevokeRace Conflict = "revoke_race"
+)
+
+// a spaces action from a mempool tx
+type Action struct {
+	Name          pgtype.Text
+	Txid          Bytes
+	Seq           int64
+	Action        db.NullCovenantAction
+	Value         pgtype.Int8
+	Scriptpubkey  *Bytes
+	BurnIncrement pgtype.Int8
+	TotalBurned   pgtype.Int8
+	ClaimHeight   pgtype.Int8
+	ExpireHeight  pgtype.Int8
+	Reason        pgtype.Text
+	ScriptError   pgtype.Text
+	Data          *Bytes
+	DataDecoded   pgtype.Text
+	DataFormat    pgtype.Text
+}
+
+// the state of a space, either confirmed or predicted from the mempool
+type SpaceState struct {
+	Txid         Bytes
+	Action       db.NullCovenantAction
+	Value        pgtype.Int8
+	Scriptpubkey *Bytes
+	TotalBurned  pgtype.Int8
+	ClaimHeight  pgtype.Int8
+	ExpireHeight pgtype.Int8
+	// data attached by the latest transfer
+	Data        *Bytes
+	DataDecoded pgtype.Text
+	DataFormat  pgtype.Text
+	// height of the block confirming the state, -1 for predicted states
+	BlockHeight int32
+}
+
+type SpaceActions struct {
+	Name      string
+	Current   *SpaceState
+	Pending   []Action
+	Conflicts []Conflict
+	Predicted *SpaceState
+}
+
+// GetPendingActions groups the mempool spaces actions by name, marks the conflicting ones and
+// predicts the state of every space once its pending actions confirm.
+// If no names are given the whole mempool is returned.
+func GetPendingActions(ctx context.Context, q *db.Queries, names ...string) ([]SpaceActions, error) {
+	var pending []Action
+	if len(names) == 0 {
+		rows, err := q.GetMempoolSpaceActions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		copier.Copy(&pending, &rows)
+	} else {
+		rows, err := q.GetMempoolSpaceActionsByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		copier.Copy(&pending, &rows)
+	}
+
+	// rows come ordered by name, then by mempool order
+	names = make([]string, 0)
+	for _, action := range pending {
+		if len(names) == 0 || names[len(names)-1] != action.Name.String {
+			names = append(names, action.Name.String)
+		}
+	}
+	if len(names) == 0 {
+		return []SpaceActions{}, nil
+	}
+
+	states, err := q.GetLatestSpaceStates(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*SpaceState, len(states))
+	for _, row := range states {
+		state := new(SpaceState)
+		copier.Copy(state, &row)
+		current[row.Name.String] = state
+	}
+
+	result := make([]SpaceActions, 0, len(names))
+	i := 0
+	for _, name := range names {
+		group := SpaceActions{Name: name, Current: current[name]}
+		for i < len(pending) && pending[i].Name.String == name {
+			group.Pending = append(group.Pending, pending[i])
+			i++
+		}
+		group.Conflicts = detectConflicts(group.Pending)
+		group.Predicted = predictState(group.Current, group.Pending)
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+// detectConflicts returns the conflicts between pending actions of different txs on the same space
+func detectConflicts(actions []Action) []Conflict {
+	txsByAction := make(map[db.CovenantAction]map[string]struct{})
+	txs := make(map[string]struct{})
+	for _, action := range actions {
+		if !action.Action.Valid || action.Action.CovenantAction == db.CovenantActionREJECT {
+			continue
+		}
+		kind := action.Action.CovenantAction
+		if txsByAction[kind] == nil {
+			txsByAction[kind] = make(map[string]struct{})
+		}
+		txsByAction[kind][action.Txid.String()] = struct{}{}
+		txs[action.Txid.String()] = struct{}{}
+	}
+
+	conflicts := make([]Conflict, 0)
+	if len(txsByAction[db.CovenantActionBID]) > 1 {
+		conflicts = append(conflicts, ConflictCompetingBids)
+	}
+	if len(txsByAction[db.CovenantActionTRANSFER]) > 1 {
+		conflicts = append(conflicts, ConflictCompetingTransfers)
+	}
+	if len(txsByAction[db.CovenantActionTRANSFER]) > 0 && len(txsByAction[db.CovenantActionBID]) > 0 {
+		conflicts = append(conflicts, ConflictTransferBidRace)
+	}
+	if len(txsByAction[db.CovenantActionREVOKE]) > 0 && len(txs) > 1 {
+		conflicts = append(conflicts, ConflictRevokeRace)
+	}
+	return conflicts
+}
+
+// predictState applies the pending actions in mempool order on top of the current state.
+// Out of competing bids only the highest one is applied, out of competing transfers the first seen.
+func predictState(current *SpaceState, actions []Action) *SpaceState {
+	if len(actions) == 0 {
+		return current
+	}
+	predicted := &SpaceState{}
+	if current != nil {
+		*predicted = *current
+	}
+	predicted.BlockHeight = -1
+
+	var winningBid *Action
+	for i, action := range actions {
+		if action.Action.Valid && action.Action.CovenantAction == db.CovenantActionBID {
+			if winningBid == nil || action.TotalBurned.Int64 > winningBid.TotalBurned.Int64 {
+				winningBid = &actions[i]
+			}
+		}
+	}
+
+	var transferTxid Bytes
+	for i, action := range actions {
+		if !action.Action.Valid {
+			continue
+		}
+		switch action.Action.CovenantAction {
+		case db.CovenantActionREJECT:
+			continue
+		case db.CovenantActionBID:
+			if &actions[i] != winningBid {
+				continue
+			}
+		case db.CovenantActionTRANSFER:
+			if transferTxid != nil && !bytes.Equal(transferTxid, action.Txid) {
+				continue
+			}
+			transferTxid = action.Txid
+		case db.CovenantActionREVOKE:
+			predicted.Txid = action.Txid
+			predicted.Action = action.Action
+			return predicted
+		}
+		applyAction(predicted, &action)
+	}
+	return predicted
+}
+
+func applyAction(state *SpaceState, action *Action) {
+	state.Txid = action.Txid
+	state.Action = action.Action
+	if action.Value.Valid {
+		state.Value = action.Value
+	}
+	if action.Scriptpubkey != nil {
+		state.Scriptpubkey = action.Scriptpubkey
+	}
+	if action.TotalBurned.Valid {
+		state.TotalBurned = action.TotalBurned
+	}
+	if action.ClaimHeight.Valid {
+		state.ClaimHeight = action.ClaimHeight
+	}
+	if action.ExpireHeight.Valid {
+		state.ExpireHeight = action.ExpireHeight
+	}
+	// a transfer carries the whole data of the space, without any it clears it
+	if action.Action.CovenantAction == db.CovenantActionTRANSFER {
+		state.Data = action.Data
+		state.DataDecoded = action.DataDecoded
+		state.DataFormat = action.DataFormat
+	}
+}
@@ -0,0 +1,225 @@
+package pending
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+func txid(b byte) Bytes {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func action(tx byte, kind db.CovenantAction) Action {
+	return Action{
+		Name:   pgtype.Text{String: "example", Valid: true},
+		Txid:   txid(tx),
+		Action: db.NullCovenantAction{CovenantAction: kind, Valid: true},
+	}
+}
+
+func bid(tx byte, totalBurned int64) Action {
+	a := action(tx, db.CovenantActionBID)
+	a.TotalBurned = pgtype.Int8{Int64: totalBurned, Valid: true}
+	return a
+}
+
+func transfer(tx byte, value int64) Action {
+	a := action(tx, db.CovenantActionTRANSFER)
+	a.Value = pgtype.Int8{Int64: value, Valid: true}
+	return a
+}
+
+func TestDetectConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		want    []Conflict
+	}{
+		{
+			name:    "no actions",
+			actions: nil,
+			want:    []Conflict{},
+		},
+		{
+			name:    "single bid",
+			actions: []Action{bid(1, 100)},
+			want:    []Conflict{},
+		},
+		{
+			name:    "bids of different txs",
+			actions: []Action{bid(1, 100), bid(2, 200)},
+			want:    []Conflict{ConflictCompetingBids},
+		},
+		{
+			name:    "bids of the same tx",
+			actions: []Action{bid(1, 100), bid(1, 200)},
+			want:    []Conflict{},
+		},
+		{
+			name:    "transfers of different txs",
+			actions: []Action{transfer(1, 1000), transfer(2, 2000)},
+			want:    []Conflict{ConflictCompetingTransfers},
+		},
+		{
+			name:    "transfer next to a bid",
+			actions: []Action{bid(1, 100), transfer(2, 1000)},
+			want:    []Conflict{ConflictTransferBidRace},
+		},
+		{
+			name:    "revoke next to a bid",
+			actions: []Action{bid(1, 100), action(2, db.CovenantActionREVOKE)},
+			want:    []Conflict{ConflictRevokeRace},
+		},
+		{
+			name:    "revoke alone",
+			actions: []Action{action(1, db.CovenantActionREVOKE)},
+			want:    []Conflict{},
+		},
+		{
+			name:    "rejected spends are ignored",
+			actions: []Action{bid(1, 100), action(2, db.CovenantActionREJECT)},
+			want:    []Conflict{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectConflicts(tt.actions)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectConflicts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPredictState(t *testing.T) {
+	current := &SpaceState{
+		Txid:        txid(9),
+		Action:      db.NullCovenantAction{CovenantAction: db.CovenantActionREGISTER, Valid: true},
+		Value:       pgtype.Int8{Int64: 500, Valid: true},
+		TotalBurned: pgtype.Int8{Int64: 50, Valid: true},
+		BlockHeight: 100,
+	}
+	data := Bytes("data")
+	withData := transfer(1, 1000)
+	withData.Data = &data
+	withData.DataFormat = pgtype.Text{String: "text", Valid: true}
+
+	tests := []struct {
+		name            string
+		current         *SpaceState
+		actions         []Action
+		wantTxid        Bytes
+		wantAction      db.CovenantAction
+		wantValue       int64
+		wantTotalBurned int64
+		wantData        *Bytes
+	}{
+		{
+			name:            "highest total burned bid wins",
+			current:         current,
+			actions:         []Action{bid(1, 100), bid(2, 300), bid(3, 200)},
+			wantTxid:        txid(2),
+			wantAction:      db.CovenantActionBID,
+			wantValue:       500,
+			wantTotalBurned: 300,
+		},
+		{
+			name:            "first bid wins a tie",
+			current:         current,
+			actions:         []Action{bid(1, 100), bid(2, 100)},
+			wantTxid:        txid(1),
+			wantAction:      db.CovenantActionBID,
+			wantValue:       500,
+			wantTotalBurned: 100,
+		},
+		{
+			name:            "first transfer wins",
+			current:         current,
+			actions:         []Action{transfer(1, 1000), transfer(2, 2000)},
+			wantTxid:        txid(1),
+			wantAction:      db.CovenantActionTRANSFER,
+			wantValue:       1000,
+			wantTotalBurned: 50,
+		},
+		{
+			name:            "transfer carries its data",
+			current:         current,
+			actions:         []Action{withData},
+			wantTxid:        txid(1),
+			wantAction:      db.CovenantActionTRANSFER,
+			wantValue:       1000,
+			wantTotalBurned: 50,
+			wantData:        &data,
+		},
+		{
+			name:            "revoke returns early",
+			current:         current,
+			actions:         []Action{action(1, db.CovenantActionREVOKE), transfer(2, 2000), bid(3, 300)},
+			wantTxid:        txid(1),
+			wantAction:      db.CovenantActionREVOKE,
+			wantValue:       500,
+			wantTotalBurned: 50,
+		},
+		{
+			name:            "rejected spends are skipped",
+			current:         current,
+			actions:         []Action{action(1, db.CovenantActionREJECT)},
+			wantTxid:        txid(9),
+			wantAction:      db.CovenantActionREGISTER,
+			wantValue:       500,
+			wantTotalBurned: 50,
+		},
+		{
+			name:            "no confirmed state",
+			current:         nil,
+			actions:         []Action{bid(1, 100)},
+			wantTxid:        txid(1),
+			wantAction:      db.CovenantActionBID,
+			wantTotalBurned: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := predictState(tt.current, tt.actions)
+			if got == tt.current {
+				t.Fatal("predictState() returned the current state")
+			}
+			if !bytes.Equal(got.Txid, tt.wantTxid) {
+				t.Errorf("txid = %s, want %s", got.Txid, tt.wantTxid)
+			}
+			if got.Action.CovenantAction != tt.wantAction {
+				t.Errorf("action = %s, want %s", got.Action.CovenantAction, tt.wantAction)
+			}
+			if got.Value.Int64 != tt.wantValue {
+				t.Errorf("value = %d, want %d", got.Value.Int64, tt.wantValue)
+			}
+			if got.TotalBurned.Int64 != tt.wantTotalBurned {
+				t.Errorf("total burned = %d, want %d", got.TotalBurned.Int64, tt.wantTotalBurned)
+			}
+			if !reflect.DeepEqual(got.Data, tt.wantData) {
+				t.Errorf("data = %v, want %v", got.Data, tt.wantData)
+			}
+			if got.BlockHeight != -1 {
+				t.Errorf("block height = %d, want -1", got.BlockHeight)
+			}
+		})
+	}
+
+	t.Run("without actions the current state is kept", func(t *testing.T) {
+		if got := predictState(current, nil); got != current {
+			t.Errorf("predictState() = %v, want the current state", got)
+		}
+	})
+	t.Run("the current state is not modified", func(t *testing.T) {
+		before := *current
+		predictState(current, []Action{transfer(1, 1000)})
+		if !reflect.DeepEqual(*current, before) {
+			t.Errorf("current state changed to %v", *current)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// exit codes shared by all commands
+const (
+	ExitOK = 0
+	// the command failed
+	ExitError = 1
+	// a batch command was stopped by a signal before it finished its work
+	ExitInterrupted = 130
+)
+
+// Context returns a context cancelled on SIGINT or SIGTERM.
+// A second signal is not caught anymore and kills the process.
+func Context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// Detach returns a context that is not cancelled by a shutdown signal and expires after timeout,
+// work that must not be cut in half, like a db commit, runs with it
+func Detach(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), timeout)
+}
+
+// Sleep waits for d, it returns false if ctx got cancelled first
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
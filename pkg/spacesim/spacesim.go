@@ -0,0 +1,349 @@
+// Package spacesim simulates the spaces protocol on top of a nodetest.Chain: scripted opens, bids,
+// claims, transfers, renewals and revocations become blocks and block metas as spaced would report
+// them, while the simulator keeps the state every space is expected to reach in the db.
+package spacesim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node/nodetest"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// ErrRejected is returned for an action the protocol refuses, the simulator
+// still mines it as a spend spaced rejected
+var ErrRejected = errors.New("rejected")
+
+// Params are the protocol intervals, in blocks
+type Params struct {
+	// length of an auction once the space is rolled out
+	AuctionBlocks int
+	// a bid closer than this to the claim height pushes the claim height this far
+	ExtensionBlocks int
+	// a claimed space expires after this many blocks unless renewed
+	RenewalBlocks int
+}
+
+var DefaultParams = Params{
+	AuctionBlocks:   10,
+	ExtensionBlocks: 2,
+	RenewalBlocks:   50,
+}
+
+type Phase string
+
+const (
+	PhaseOpen    Phase = "open"
+	PhaseAuction Phase = "auction"
+	PhaseClaimed Phase = "claimed"
+	PhaseRevoked Phase = "revoked"
+)
+
+// Space is the state of a space after the last accepted action,
+// the covenant fields are the ones of its latest vmetaout
+type Space struct {
+	Name   string
+	Phase  Phase
+	Action string
+	Owner  Bytes
+	Value  int
+
+	Priority      int
+	BurnIncrement *int
+	TotalBurned   *int
+	ClaimHeight   *int
+	ExpireHeight  *int
+	Reason        string
+}
+
+type action struct {
+	tx   node.Transaction
+	meta node.MetaTransaction
+}
+
+// Simulator turns protocol actions into the next block of its chain
+type Simulator struct {
+	Chain  *nodetest.Chain
+	Params Params
+
+	spaces  map[string]Space
+	pending []action
+	// state of the spaces after each block, to roll back reorgs
+	history map[int32]map[string]Space
+}
+
+func New(chain *nodetest.Chain, params Params) *Simulator {
+	sim := &Simulator{
+		Chain:   chain,
+		Params:  params,
+		spaces:  make(map[string]Space),
+		history: make(map[int32]map[string]Space),
+	}
+	sim.history[chain.Height()] = copySpaces(sim.spaces)
+	return sim
+}
+
+// Height returns the height the pending actions will be mined at
+func (sim *Simulator) Height() int {
+	return int(sim.Chain.Height()) + 1
+}
+
+// Mine mines the pending actions into a block and returns it
+func (sim *Simulator) Mine() *node.Block {
+	var txs []node.Transaction
+	var metas []node.MetaTransaction
+	for _, a := range sim.pending {
+		txs = append(txs, a.tx)
+		metas = append(metas, a.meta)
+	}
+	block := sim.Chain.MineMeta(metas, txs...)
+	sim.pending = nil
+	sim.history[block.Height] = copySpaces(sim.spaces)
+	return block
+}
+
+// MineN mines the pending actions and n-1 empty blocks after them
+func (sim *Simulator) MineN(n int) *node.Block {
+	var block *node.Block
+	for i := 0; i < n; i++ {
+		block = sim.Mine()
+	}
+	return block
+}
+
+// MineUntil mines blocks until the tip is at height
+func (sim *Simulator) MineUntil(height int) *node.Block {
+	block := sim.Chain.Tip()
+	for int(block.Height) < height {
+		block = sim.Mine()
+	}
+	return block
+}
+
+// Reorg disconnects the top depth blocks and rolls the spaces back to the new tip,
+// the pending actions are dropped
+func (sim *Simulator) Reorg(depth int) {
+	sim.Chain.Reorg(depth)
+	tip := sim.Chain.Height()
+	for height := range sim.history {
+		if height > tip {
+			delete(sim.history, height)
+		}
+	}
+	sim.spaces = copySpaces(sim.history[tip])
+	sim.pending = nil
+}
+
+// Space returns the expected state of a space, false if it was never opened
+func (sim *Simulator) Space(name string) (Space, bool) {
+	space, exists := sim.spaces[normalize(name)]
+	return space, exists
+}
+
+// Spaces returns the expected state of every space, ordered by name
+func (sim *Simulator) Spaces() []Space {
+	spaces := make([]Space, 0, len(sim.spaces))
+	for _, space := range sim.spaces {
+		spaces = append(spaces, space)
+	}
+	sort.Slice(spaces, func(i, j int) bool { return spaces[i].Name < spaces[j].Name })
+	return spaces
+}
+
+// Open opens a space for auction with a first bid
+func (sim *Simulator) Open(name string, amount int, owner Bytes) error {
+	name = normalize(name)
+	if space, exists := sim.spaces[name]; exists && space.Phase != PhaseRevoked {
+		return sim.reject(name, "space already exists")
+	}
+	burn := amount
+	return sim.update(Space{
+		Name:          name,
+		Phase:         PhaseOpen,
+		Action:        "BID",
+		Owner:         owner,
+		Value:         amount,
+		BurnIncrement: &burn,
+		TotalBurned:   intPtr(amount),
+	})
+}
+
+// Rollout starts the auction of an open space, it can be claimed AuctionBlocks later
+func (sim *Simulator) Rollout(name string, priority int) error {
+	space, err := sim.get(name, PhaseOpen)
+	if err != nil {
+		return err
+	}
+	space.Phase = PhaseAuction
+	space.Action = "ROLLOUT"
+	space.Priority = priority
+	space.BurnIncrement = nil
+	space.ClaimHeight = intPtr(sim.Height() + sim.Params.AuctionBlocks)
+	return sim.update(space)
+}
+
+// Bid outbids the current bid of an open or auctioned space
+func (sim *Simulator) Bid(name string, amount int, owner Bytes) error {
+	space, err := sim.get(name, PhaseOpen, PhaseAuction)
+	if err != nil {
+		return err
+	}
+	if amount <= space.Value {
+		return sim.reject(space.Name, fmt.Sprintf("bid %d does not outbid %d", amount, space.Value))
+	}
+	height := sim.Height()
+	if space.ClaimHeight != nil {
+		if height >= *space.ClaimHeight {
+			return sim.reject(space.Name, "auction is over")
+		}
+		if *space.ClaimHeight-height < sim.Params.ExtensionBlocks {
+			space.ClaimHeight = intPtr(height + sim.Params.ExtensionBlocks)
+		}
+	}
+	space.Action = "BID"
+	space.BurnIncrement = intPtr(amount - space.Value)
+	space.TotalBurned = intPtr(amount)
+	space.Value = amount
+	space.Owner = owner
+	return sim.update(space)
+}
+
+// Claim gives an auctioned space to its highest bidder once the claim height is reached
+func (sim *Simulator) Claim(name string) error {
+	space, err := sim.get(name, PhaseAuction)
+	if err != nil {
+		return err
+	}
+	if sim.Height() < *space.ClaimHeight {
+		return sim.reject(space.Name, fmt.Sprintf("claimable at %d", *space.ClaimHeight))
+	}
+	space.Phase = PhaseClaimed
+	space.Action = "TRANSFER"
+	space.BurnIncrement = nil
+	space.ExpireHeight = intPtr(sim.Height() + sim.Params.RenewalBlocks)
+	return sim.update(space)
+}
+
+// Transfer moves a claimed space to a new owner
+func (sim *Simulator) Transfer(name string, owner Bytes) error {
+	space, err := sim.live(name)
+	if err != nil {
+		return err
+	}
+	space.Owner = owner
+	return sim.update(space)
+}
+
+// Renew pushes the expire height of a claimed space
+func (sim *Simulator) Renew(name string) error {
+	space, err := sim.live(name)
+	if err != nil {
+		return err
+	}
+	space.ExpireHeight = intPtr(sim.Height() + sim.Params.RenewalBlocks)
+	return sim.update(space)
+}
+
+// Revoke revokes a space, it can be opened again afterwards
+func (sim *Simulator) Revoke(name string, reason string) error {
+	space, err := sim.get(name, PhaseOpen, PhaseAuction, PhaseClaimed)
+	if err != nil {
+		return err
+	}
+	space.Phase = PhaseRevoked
+	space.Action = "REVOKE"
+	space.BurnIncrement = nil
+	space.Reason = reason
+	return sim.update(space)
+}
+
+// get returns a space in one of the phases, rejecting the action otherwise
+func (sim *Simulator) get(name string, phases ...Phase) (Space, error) {
+	name = normalize(name)
+	space, exists := sim.spaces[name]
+	if !exists {
+		return Space{}, sim.reject(name, "space does not exist")
+	}
+	for _, phase := range phases {
+		if space.Phase == phase {
+			space.Reason = ""
+			return space, nil
+		}
+	}
+	return Space{}, sim.reject(name, fmt.Sprintf("space is %s", space.Phase))
+}
+
+// live returns a claimed space that has not expired
+func (sim *Simulator) live(name string) (Space, error) {
+	space, err := sim.get(name, PhaseClaimed)
+	if err != nil {
+		return Space{}, err
+	}
+	if sim.Height() > *space.ExpireHeight {
+		return Space{}, sim.reject(space.Name, fmt.Sprintf("expired at %d", *space.ExpireHeight))
+	}
+	space.Action = "TRANSFER"
+	space.BurnIncrement = nil
+	return space, nil
+}
+
+// update queues the tx moving a space to its new state
+func (sim *Simulator) update(space Space) error {
+	tx := sim.Chain.NewTx()
+	covenant := node.Covenant{
+		Type:          strings.ToLower(space.Action),
+		BurnIncrement: space.BurnIncrement,
+		TotalBurned:   space.TotalBurned,
+		ClaimHeight:   space.ClaimHeight,
+		ExpireHeight:  space.ExpireHeight,
+		Signature:     Bytes{0x30},
+	}
+	update := node.UpdateMeta{
+		Type:     strings.ToLower(space.Action),
+		Priority: space.Priority,
+		Reason:   space.Reason,
+		Output: node.OutputMeta{
+			TxID:         tx.Txid,
+			N:            0,
+			Covenant:     covenant,
+			Value:        space.Value,
+			Name:         "@" + space.Name,
+			ScriptPubKey: space.Owner,
+		},
+	}
+	sim.pending = append(sim.pending, action{tx: tx, meta: node.MetaTransaction{TxID: tx.Txid, Updates: []node.UpdateMeta{update}}})
+	sim.spaces[space.Name] = space
+	return nil
+}
+
+// reject queues a spend spaced rejected, the space keeps its state
+func (sim *Simulator) reject(name string, reason string) error {
+	tx := sim.Chain.NewTx()
+	meta := node.MetaTransaction{TxID: tx.Txid}
+	meta.Spends = append(meta.Spends, struct {
+		N           int               `json:"n"`
+		ScriptError *node.ScriptError `json:"script_error,omitempty"`
+	}{N: 0, ScriptError: &node.ScriptError{Type: "reject", Name: "@" + name, Reason: reason}})
+	sim.pending = append(sim.pending, action{tx: tx, meta: meta})
+	return fmt.Errorf("@%s: %w: %s", name, ErrRejected, reason)
+}
+
+func normalize(name string) string {
+	return strings.TrimPrefix(strings.ToLower(name), "@")
+}
+
+func copySpaces(spaces map[string]Space) map[string]Space {
+	copied := make(map[string]Space, len(spaces))
+	for name, space := range spaces {
+		copied[name] = space
+	}
+	return copied
+}
+
+func intPtr(i int) *int {
+	return &i
+}
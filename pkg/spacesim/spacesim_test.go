@@ -0,0 +1,232 @@
+package spacesim_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/node/nodetest"
+	"github.com/spacesprotocol/explorer-indexer/pkg/spacesim"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+var alice, bob, carol = Bytes{0x51, 0x01}, Bytes{0x51, 0x02}, Bytes{0x51, 0x03}
+
+func newSim(t *testing.T) *spacesim.Simulator {
+	t.Helper()
+	return spacesim.New(nodetest.NewChain(), spacesim.DefaultParams)
+}
+
+func space(t *testing.T, sim *spacesim.Simulator, name string) spacesim.Space {
+	t.Helper()
+	space, exists := sim.Space(name)
+	if !exists {
+		t.Fatalf("%s does not exist", name)
+	}
+	return space
+}
+
+// rolledOut opens @alpha with a bid of 1000 from alice and rolls it out, the claim height
+// is AuctionBlocks after the rollout block
+func rolledOut(t *testing.T, sim *spacesim.Simulator) int {
+	t.Helper()
+	if err := sim.Open("@alpha", 1000, alice); err != nil {
+		t.Fatal(err)
+	}
+	sim.Mine()
+	rolloutHeight := sim.Height()
+	if err := sim.Rollout("@alpha", 1); err != nil {
+		t.Fatal(err)
+	}
+	sim.Mine()
+	return rolloutHeight + sim.Params.AuctionBlocks
+}
+
+func TestOutbid(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      int
+		wantErr     bool
+		wantValue   int
+		wantOwner   Bytes
+		wantBurnInc int
+	}{
+		{"higher bid wins", 1500, false, 1500, bob, 500},
+		{"equal bid is rejected", 1000, true, 1000, alice, 1000},
+		{"lower bid is rejected", 900, true, 1000, alice, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			if err := sim.Open("@alpha", 1000, alice); err != nil {
+				t.Fatal(err)
+			}
+			sim.Mine()
+
+			err := sim.Bid("@alpha", tt.amount, bob)
+			if tt.wantErr != errors.Is(err, spacesim.ErrRejected) {
+				t.Fatalf("bid err = %v, want rejected %v", err, tt.wantErr)
+			}
+			block := sim.Mine()
+			if len(block.Transactions) != 2 {
+				t.Errorf("block has %d txs, want the coinbase and the bid", len(block.Transactions))
+			}
+
+			got := space(t, sim, "@alpha")
+			if got.Value != tt.wantValue || !bytes.Equal(got.Owner, tt.wantOwner) {
+				t.Errorf("space is %d owned by %s, want %d owned by %s", got.Value, got.Owner, tt.wantValue, tt.wantOwner)
+			}
+			if *got.BurnIncrement != tt.wantBurnInc || *got.TotalBurned != tt.wantValue {
+				t.Errorf("burn increment %d, total burned %d, want %d and %d", *got.BurnIncrement, *got.TotalBurned, tt.wantBurnInc, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestClaimHeight(t *testing.T) {
+	sim := newSim(t)
+	claimHeight := rolledOut(t, sim)
+
+	got := space(t, sim, "@alpha")
+	if got.Phase != spacesim.PhaseAuction || got.ClaimHeight == nil || *got.ClaimHeight != claimHeight {
+		t.Fatalf("rolled out space is %s with claim height %v, want auction at %d", got.Phase, got.ClaimHeight, claimHeight)
+	}
+
+	// an early claim is rejected
+	if err := sim.Claim("@alpha"); !errors.Is(err, spacesim.ErrRejected) {
+		t.Fatalf("early claim err = %v, want rejected", err)
+	}
+	sim.MineUntil(claimHeight - 1)
+	if err := sim.Claim("@alpha"); err != nil {
+		t.Fatalf("claim at %d: %v", sim.Height(), err)
+	}
+	sim.Mine()
+
+	got = space(t, sim, "@alpha")
+	if got.Phase != spacesim.PhaseClaimed || got.Action != "TRANSFER" {
+		t.Errorf("claimed space is %s after %s, want claimed after TRANSFER", got.Phase, got.Action)
+	}
+	if want := claimHeight + sim.Params.RenewalBlocks; *got.ExpireHeight != want {
+		t.Errorf("expire height = %d, want %d", *got.ExpireHeight, want)
+	}
+
+	// the auction is over once claimed
+	if err := sim.Bid("@alpha", 5000, carol); !errors.Is(err, spacesim.ErrRejected) {
+		t.Errorf("bid on a claimed space err = %v, want rejected", err)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		// blocks between the bid and the claim height
+		blocksLeft      int
+		wantClaimHeight func(claimHeight int, bidHeight int) int
+	}{
+		{"far from the claim height", 5, func(claimHeight int, bidHeight int) int { return claimHeight }},
+		{"at the extension window", 2, func(claimHeight int, bidHeight int) int { return claimHeight }},
+		{"inside the extension window", 1, func(claimHeight int, bidHeight int) int {
+			return bidHeight + spacesim.DefaultParams.ExtensionBlocks
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSim(t)
+			claimHeight := rolledOut(t, sim)
+			sim.MineUntil(claimHeight - tt.blocksLeft - 1)
+
+			bidHeight := sim.Height()
+			if err := sim.Bid("@alpha", 2000, bob); err != nil {
+				t.Fatal(err)
+			}
+			sim.Mine()
+			got := space(t, sim, "@alpha")
+			if want := tt.wantClaimHeight(claimHeight, bidHeight); *got.ClaimHeight != want {
+				t.Errorf("claim height = %d, want %d", *got.ClaimHeight, want)
+			}
+		})
+	}
+
+	t.Run("bid at the claim height", func(t *testing.T) {
+		sim := newSim(t)
+		claimHeight := rolledOut(t, sim)
+		sim.MineUntil(claimHeight - 1)
+		if err := sim.Bid("@alpha", 2000, bob); !errors.Is(err, spacesim.ErrRejected) {
+			t.Errorf("bid err = %v, want rejected", err)
+		}
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	sim := newSim(t)
+	rolledOut(t, sim)
+
+	if err := sim.Revoke("@alpha", "bid psbt spent"); err != nil {
+		t.Fatal(err)
+	}
+	sim.Mine()
+	got := space(t, sim, "@alpha")
+	if got.Phase != spacesim.PhaseRevoked || got.Action != "REVOKE" || got.Reason != "bid psbt spent" {
+		t.Errorf("revoked space is %s after %s (%q)", got.Phase, got.Action, got.Reason)
+	}
+
+	// nothing but a new open applies to a revoked space
+	if err := sim.Bid("@alpha", 5000, bob); !errors.Is(err, spacesim.ErrRejected) {
+		t.Errorf("bid on a revoked space err = %v, want rejected", err)
+	}
+	if err := sim.Revoke("@alpha", "again"); !errors.Is(err, spacesim.ErrRejected) {
+		t.Errorf("second revoke err = %v, want rejected", err)
+	}
+	if err := sim.Open("@alpha", 700, carol); err != nil {
+		t.Fatalf("open after revoke: %v", err)
+	}
+	sim.Mine()
+	got = space(t, sim, "@alpha")
+	if got.Phase != spacesim.PhaseOpen || got.Reason != "" || !bytes.Equal(got.Owner, carol) {
+		t.Errorf("reopened space is %s owned by %s (%q)", got.Phase, got.Owner, got.Reason)
+	}
+}
+
+func TestReorgRollback(t *testing.T) {
+	sim := newSim(t)
+	if err := sim.Open("@alpha", 1000, alice); err != nil {
+		t.Fatal(err)
+	}
+	sim.Mine()
+	if err := sim.Bid("@alpha", 2000, bob); err != nil {
+		t.Fatal(err)
+	}
+	sim.Mine()
+	if err := sim.Open("@beta", 500, carol); err != nil {
+		t.Fatal(err)
+	}
+	sim.Mine()
+	// pending actions are dropped by the reorg
+	if err := sim.Bid("@alpha", 3000, carol); err != nil {
+		t.Fatal(err)
+	}
+
+	sim.Reorg(2)
+	got := space(t, sim, "@alpha")
+	if got.Value != 1000 || !bytes.Equal(got.Owner, alice) {
+		t.Errorf("space rolled back to %d owned by %s, want the open bid of alice", got.Value, got.Owner)
+	}
+	if _, exists := sim.Space("@beta"); exists {
+		t.Error("space opened in a disconnected block still exists")
+	}
+	if block := sim.Mine(); len(block.Transactions) != 1 {
+		t.Errorf("first block after the reorg has %d txs, want only the coinbase", len(block.Transactions))
+	}
+
+	// the new chain goes on from the rolled back state
+	if err := sim.Bid("@alpha", 1500, carol); err != nil {
+		t.Fatalf("bid after the reorg: %v", err)
+	}
+	sim.Mine()
+	if got := space(t, sim, "@alpha"); got.Value != 1500 || *got.BurnIncrement != 500 {
+		t.Errorf("space is %d with burn increment %d, want 1500 and 500", got.Value, *got.BurnIncrement)
+	}
+	if spaces := sim.Spaces(); len(spaces) != 1 {
+		t.Errorf("%d spaces, want 1", len(spaces))
+	}
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// formats of the covenant data the indexer decodes, invalid when spaced reported no hex string
+const (
+	DataFormatJSON    = "json"
+	DataFormatText    = "text"
+	DataFormatInvalid = "invalid"
+)
+
+// covenantActions maps the covenant and update types of the spaces protocol to the covenant_action enum,
+// a type added to spaced needs an entry here and a value in the enum
+var covenantActions = map[string]db.CovenantAction{
+	"open":     db.CovenantActionOPEN,
+	"bid":      db.CovenantActionBID,
+	"reserve":  db.CovenantActionRESERVE,
+	"rollout":  db.CovenantActionROLLOUT,
+	"register": db.CovenantActionREGISTER,
+	"transfer": db.CovenantActionTRANSFER,
+	"revoke":   db.CovenantActionREVOKE,
+}
+
+// mapCovenantAction returns the action of a covenant or update type, a type missing from
+// covenantActions is stored as UNKNOWN with its name as the raw action instead of failing the block
+func mapCovenantAction(covenantType string) (db.NullCovenantAction, pgtype.Text) {
+	if action, known := covenantActions[strings.ToLower(covenantType)]; known {
+		return db.NullCovenantAction{CovenantAction: action, Valid: true}, pgtype.Text{}
+	}
+	// the type comes from spaced, it is logged rather than used as a label to keep the series bounded
+	logging.Component("store").Warn("unknown covenant type", "type", covenantType)
+	metrics.UnknownCovenantActions.WithLabelValues("unknown").Inc()
+	return db.NullCovenantAction{CovenantAction: db.CovenantActionUNKNOWN, Valid: true}, pgtype.Text{String: covenantType, Valid: true}
+}
+
+// covenantData returns the raw bytes of a covenant data payload, spaced reports them as a hex string,
+// and their decoded form when they are a json document or printable text.
+// A payload that is not a hex string is not stored as data, it is kept as reported with the invalid format
+func covenantData(data interface{}) (raw *Bytes, decoded pgtype.Text, format pgtype.Text) {
+	if data == nil {
+		return nil, pgtype.Text{}, pgtype.Text{}
+	}
+	payload, isString := data.(string)
+	b, err := hex.DecodeString(payload)
+	if !isString || err != nil {
+		reported, _ := json.Marshal(data)
+		logging.Component("store").Warn("covenant data is not hex", "data", string(reported))
+		return nil, pgtype.Text{String: string(reported), Valid: true}, pgtype.Text{String: DataFormatInvalid, Valid: true}
+	}
+	raw = (*Bytes)(&b)
+
+	trimmed := bytes.TrimSpace(*raw)
+	switch {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed):
+		return raw, pgtype.Text{String: string(*raw), Valid: true}, pgtype.Text{String: DataFormatJSON, Valid: true}
+	case len(*raw) > 0 && isPrintable(*raw):
+		return raw, pgtype.Text{String: string(*raw), Valid: true}, pgtype.Text{String: DataFormatText, Valid: true}
+	}
+	return raw, pgtype.Text{}, pgtype.Text{}
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
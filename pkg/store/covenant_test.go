@@ -0,0 +1,85 @@
+package store
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
+)
+
+func text(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: true}
+}
+
+func TestMapCovenantAction(t *testing.T) {
+	tests := []struct {
+		name         string
+		covenantType string
+		want         db.CovenantAction
+		wantRaw      pgtype.Text
+		wantUnknown  float64
+	}{
+		{"known type", "bid", db.CovenantActionBID, pgtype.Text{}, 0},
+		{"known type in capitals", "TRANSFER", db.CovenantActionTRANSFER, pgtype.Text{}, 0},
+		{"unknown type", "delegate", db.CovenantActionUNKNOWN, text("delegate"), 1},
+		{"empty type", "", db.CovenantActionUNKNOWN, text(""), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknown := metrics.UnknownCovenantActions.WithLabelValues("unknown")
+			before := testutil.ToFloat64(unknown)
+
+			action, raw := mapCovenantAction(tt.covenantType)
+			if !action.Valid || action.CovenantAction != tt.want {
+				t.Errorf("action = %+v, want %s", action, tt.want)
+			}
+			if raw != tt.wantRaw {
+				t.Errorf("raw action = %+v, want %+v", raw, tt.wantRaw)
+			}
+			if got := testutil.ToFloat64(unknown) - before; got != tt.wantUnknown {
+				t.Errorf("unknown actions counted %v, want %v", got, tt.wantUnknown)
+			}
+		})
+	}
+	if series := testutil.CollectAndCount(metrics.UnknownCovenantActions); series != 1 {
+		t.Errorf("%d unknown covenant action series, want 1", series)
+	}
+}
+
+func TestCovenantData(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        interface{}
+		wantRaw     []byte
+		wantDecoded pgtype.Text
+		wantFormat  pgtype.Text
+	}{
+		{"nil", nil, nil, pgtype.Text{}, pgtype.Text{}},
+		{"json object", hex.EncodeToString([]byte(`{"a":1}`)), []byte(`{"a":1}`), text(`{"a":1}`), text(DataFormatJSON)},
+		{"json array with spaces", hex.EncodeToString([]byte(" [1, 2]\n")), []byte(" [1, 2]\n"), text(" [1, 2]\n"), text(DataFormatJSON)},
+		{"broken json is text", hex.EncodeToString([]byte(`{"a":`)), []byte(`{"a":`), text(`{"a":`), text(DataFormatText)},
+		{"text", hex.EncodeToString([]byte("hello space")), []byte("hello space"), text("hello space"), text(DataFormatText)},
+		{"binary", "00ff10", []byte{0x00, 0xff, 0x10}, pgtype.Text{}, pgtype.Text{}},
+		{"empty", "", []byte{}, pgtype.Text{}, pgtype.Text{}},
+		{"not hex", "xyz", nil, text(`"xyz"`), text(DataFormatInvalid)},
+		{"not a string", map[string]interface{}{"a": 1.0}, nil, text(`{"a":1}`), text(DataFormatInvalid)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, decoded, format := covenantData(tt.data)
+			if (raw == nil) != (tt.wantRaw == nil) || raw != nil && !bytes.Equal(*raw, tt.wantRaw) {
+				t.Errorf("raw = %v, want %x", raw, tt.wantRaw)
+			}
+			if decoded != tt.wantDecoded {
+				t.Errorf("decoded = %+v, want %+v", decoded, tt.wantDecoded)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %+v, want %+v", format, tt.wantFormat)
+			}
+		})
+	}
+}
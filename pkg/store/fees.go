@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// lower bounds of the fee rate buckets in sat/vB, the last bucket is open ended
+var feeHistogramEdges = []float64{0, 1, 2, 3, 4, 5, 6, 8, 10, 12, 15, 20, 30, 40, 50, 60, 70, 80, 90, 100, 125, 150, 200, 300, 500, 1000}
+
+// buildFeeHistogram buckets the mempool by fee rate, empty buckets are left out
+func buildFeeHistogram(entries map[string]node.MempoolTx) (db.InsertFeeSnapshotParams, []db.InsertFeeHistogramBucketsParams) {
+	snapshot := db.InsertFeeSnapshotParams{}
+	buckets := make([]db.InsertFeeHistogramBucketsParams, len(feeHistogramEdges))
+	for i, edge := range feeHistogramEdges {
+		buckets[i].FeeRateMin = edge
+		if i+1 < len(feeHistogramEdges) {
+			buckets[i].FeeRateMax = pgtype.Float8{Float64: feeHistogramEdges[i+1], Valid: true}
+		}
+	}
+
+	for _, entry := range entries {
+		fee := btcToSats(entry.Fees.Modified)
+		var feeRate float64
+		if entry.VSize > 0 {
+			feeRate = float64(fee) / float64(entry.VSize)
+		}
+
+		i := len(feeHistogramEdges) - 1
+		for i > 0 && feeRate < feeHistogramEdges[i] {
+			i--
+		}
+		buckets[i].TxCount++
+		buckets[i].TotalVsize += entry.VSize
+		buckets[i].TotalFees += fee
+
+		snapshot.TxCount++
+		snapshot.TotalVsize += entry.VSize
+		snapshot.TotalFees += fee
+	}
+
+	nonEmpty := make([]db.InsertFeeHistogramBucketsParams, 0, len(buckets))
+	for _, bucket := range buckets {
+		if bucket.TxCount > 0 {
+			nonEmpty = append(nonEmpty, bucket)
+		}
+	}
+	return snapshot, nonEmpty
+}
+
+// StoreFeeSnapshot stores the mempool fee rate histogram together with the node's fee estimates,
+// estimates the node could not compute are skipped
+func StoreFeeSnapshot(ctx context.Context, q *db.Queries, entries map[string]node.MempoolTx, estimates map[int]*node.FeeEstimate) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreFeeSnapshot", attribute.Int("mempool.txs", len(entries)))
+	defer func() { tracing.End(span, err) }()
+
+	snapshot, buckets := buildFeeHistogram(entries)
+	snapshotID, err := q.InsertFeeSnapshot(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+
+	for i := range buckets {
+		buckets[i].SnapshotID = snapshotID
+	}
+	if len(buckets) > 0 {
+		if _, err := q.InsertFeeHistogramBuckets(ctx, buckets); err != nil {
+			return err
+		}
+	}
+
+	for confTarget, estimate := range estimates {
+		if len(estimate.Errors) > 0 || estimate.FeeRate <= 0 {
+			logging.Component("store").Warn("no fee estimate", "conf_target", confTarget, "errors", estimate.Errors)
+			continue
+		}
+		params := db.InsertFeeEstimateParams{
+			SnapshotID: snapshotID,
+			ConfTarget: int32(confTarget),
+			FeeRate:    estimate.SatsPerVByte(),
+			Blocks:     int32(estimate.Blocks),
+		}
+		if err := q.InsertFeeEstimate(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneFeeSnapshots deletes the fee snapshots older than the retention period
+func PruneFeeSnapshots(ctx context.Context, q *db.Queries, retention time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "store.PruneFeeSnapshots")
+	defer func() { tracing.End(span, err) }()
+
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true}
+	return q.DeleteFeeSnapshotsBefore(ctx, cutoff)
+}
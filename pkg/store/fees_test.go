@@ -0,0 +1,83 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+)
+
+func mempoolTx(fee int64, vsize int64) node.MempoolTx {
+	return node.MempoolTx{VSize: vsize, Fees: node.MempoolFees{Modified: float64(fee) / 1e8}}
+}
+
+func bucket(min float64, max float64, txCount int32, totalVsize int64, totalFees int64) db.InsertFeeHistogramBucketsParams {
+	b := db.InsertFeeHistogramBucketsParams{FeeRateMin: min, TxCount: txCount, TotalVsize: totalVsize, TotalFees: totalFees}
+	if max > 0 {
+		b.FeeRateMax = pgtype.Float8{Float64: max, Valid: true}
+	}
+	return b
+}
+
+func TestBuildFeeHistogram(t *testing.T) {
+	tests := []struct {
+		name         string
+		entries      map[string]node.MempoolTx
+		wantSnapshot db.InsertFeeSnapshotParams
+		wantBuckets  []db.InsertFeeHistogramBucketsParams
+	}{
+		{
+			name:         "empty mempool",
+			entries:      map[string]node.MempoolTx{},
+			wantSnapshot: db.InsertFeeSnapshotParams{},
+			wantBuckets:  []db.InsertFeeHistogramBucketsParams{},
+		},
+		{
+			name:         "just below an edge",
+			entries:      map[string]node.MempoolTx{"a": mempoolTx(199, 100)},
+			wantSnapshot: db.InsertFeeSnapshotParams{TxCount: 1, TotalVsize: 100, TotalFees: 199},
+			wantBuckets:  []db.InsertFeeHistogramBucketsParams{bucket(1, 2, 1, 100, 199)},
+		},
+		{
+			name:         "on an edge",
+			entries:      map[string]node.MempoolTx{"a": mempoolTx(200, 100)},
+			wantSnapshot: db.InsertFeeSnapshotParams{TxCount: 1, TotalVsize: 100, TotalFees: 200},
+			wantBuckets:  []db.InsertFeeHistogramBucketsParams{bucket(2, 3, 1, 100, 200)},
+		},
+		{
+			name:         "above the last edge",
+			entries:      map[string]node.MempoolTx{"a": mempoolTx(500000, 100)},
+			wantSnapshot: db.InsertFeeSnapshotParams{TxCount: 1, TotalVsize: 100, TotalFees: 500000},
+			wantBuckets:  []db.InsertFeeHistogramBucketsParams{bucket(1000, 0, 1, 100, 500000)},
+		},
+		{
+			name:         "zero vsize",
+			entries:      map[string]node.MempoolTx{"a": mempoolTx(1000, 0)},
+			wantSnapshot: db.InsertFeeSnapshotParams{TxCount: 1, TotalFees: 1000},
+			wantBuckets:  []db.InsertFeeHistogramBucketsParams{bucket(0, 1, 1, 0, 1000)},
+		},
+		{
+			name: "txs sharing a bucket",
+			entries: map[string]node.MempoolTx{
+				"a": mempoolTx(1000, 100),
+				"b": mempoolTx(1100, 100),
+				"c": mempoolTx(50, 100),
+			},
+			wantSnapshot: db.InsertFeeSnapshotParams{TxCount: 3, TotalVsize: 300, TotalFees: 2150},
+			wantBuckets:  []db.InsertFeeHistogramBucketsParams{bucket(0, 1, 1, 100, 50), bucket(10, 12, 2, 200, 2100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, buckets := buildFeeHistogram(tt.entries)
+			if snapshot != tt.wantSnapshot {
+				t.Errorf("snapshot = %+v, want %+v", snapshot, tt.wantSnapshot)
+			}
+			if !reflect.DeepEqual(buckets, tt.wantBuckets) {
+				t.Errorf("buckets = %+v, want %+v", buckets, tt.wantBuckets)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"math"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func btcToSats(value float64) int64 {
+	return int64(math.Round(value * 1e8))
+}
+
+// prepareMempoolEntries converts the node's verbose mempool into column arrays for a single upsert
+func prepareMempoolEntries(entries map[string]node.MempoolTx) (db.UpsertMempoolEntriesParams, error) {
+	size := len(entries)
+	params := db.UpsertMempoolEntriesParams{
+		Txids:             make([]Bytes, 0, size),
+		FirstSeen:         make([]int64, 0, size),
+		Vsize:             make([]int64, 0, size),
+		Weight:            make([]int64, 0, size),
+		BaseFee:           make([]int64, 0, size),
+		ModifiedFee:       make([]int64, 0, size),
+		FeeRate:           make([]float64, 0, size),
+		AncestorCount:     make([]int32, 0, size),
+		AncestorSize:      make([]int64, 0, size),
+		AncestorFees:      make([]int64, 0, size),
+		DescendantCount:   make([]int32, 0, size),
+		DescendantSize:    make([]int64, 0, size),
+		DescendantFees:    make([]int64, 0, size),
+		Bip125Replaceable: make([]bool, 0, size),
+	}
+
+	for txidStr, entry := range entries {
+		var txid Bytes
+		if err := txid.UnmarshalString(txidStr); err != nil {
+			return params, err
+		}
+		modifiedFee := btcToSats(entry.Fees.Modified)
+		var feeRate float64
+		if entry.VSize > 0 {
+			feeRate = float64(modifiedFee) / float64(entry.VSize)
+		}
+
+		params.Txids = append(params.Txids, txid)
+		params.FirstSeen = append(params.FirstSeen, entry.Time)
+		params.Vsize = append(params.Vsize, entry.VSize)
+		params.Weight = append(params.Weight, entry.Weight)
+		params.BaseFee = append(params.BaseFee, btcToSats(entry.Fees.Base))
+		params.ModifiedFee = append(params.ModifiedFee, modifiedFee)
+		params.FeeRate = append(params.FeeRate, feeRate)
+		params.AncestorCount = append(params.AncestorCount, entry.AncestorCount)
+		params.AncestorSize = append(params.AncestorSize, entry.AncestorSize)
+		params.AncestorFees = append(params.AncestorFees, btcToSats(entry.Fees.Ancestor))
+		params.DescendantCount = append(params.DescendantCount, entry.DescendantCount)
+		params.DescendantSize = append(params.DescendantSize, entry.DescendantSize)
+		params.DescendantFees = append(params.DescendantFees, btcToSats(entry.Fees.Descendant))
+		params.Bip125Replaceable = append(params.Bip125Replaceable, entry.Bip125Replaceable)
+	}
+	return params, nil
+}
+
+// StoreMempoolEntries inserts the node's new mempool entries and refreshes the ones whose fees or
+// ancestors changed, the entries of txs that left the mempool are deleted with them
+func StoreMempoolEntries(ctx context.Context, q *db.Queries, entries map[string]node.MempoolTx) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreMempoolEntries", attribute.Int("mempool.txs", len(entries)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(entries) == 0 {
+		return nil
+	}
+	params, err := prepareMempoolEntries(entries)
+	if err != nil {
+		return err
+	}
+	return q.UpsertMempoolEntries(ctx, params)
+}
+
+// StoreMempoolSpends records the outpoints spent by a mempool tx so replacements can be matched later
+func StoreMempoolSpends(ctx context.Context, q *db.Queries, transaction *node.Transaction) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreMempoolSpends", attribute.String("tx.id", transaction.Txid.String()))
+	defer func() { tracing.End(span, err) }()
+
+	params := db.InsertMempoolSpendsParams{
+		Txid:        transaction.Txid,
+		PrevTxids:   make([]Bytes, 0, len(transaction.Vin)),
+		PrevIndexes: make([]int32, 0, len(transaction.Vin)),
+	}
+	for _, vin := range transaction.Vin {
+		if vin.HashPrevout == nil {
+			continue
+		}
+		params.PrevTxids = append(params.PrevTxids, *vin.HashPrevout)
+		params.PrevIndexes = append(params.PrevIndexes, int32(vin.IndexPrevout))
+	}
+	if len(params.PrevTxids) == 0 {
+		return nil
+	}
+	return q.InsertMempoolSpends(ctx, params)
+}
+
+// StoreMempoolReplacement keeps the replaced tx and its spaces outputs as history instead of deleting them
+func StoreMempoolReplacement(ctx context.Context, q *db.Queries, replacedTxid Bytes, replacingTxid Bytes, replacing node.MempoolTx) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreMempoolReplacement", attribute.String("tx.id", replacedTxid.String()), attribute.String("tx.replaced_by", replacingTxid.String()))
+	defer func() { tracing.End(span, err) }()
+
+	if err := q.InsertMempoolReplacement(ctx, db.InsertMempoolReplacementParams{
+		ReplacingTxid: replacingTxid,
+		ReplacingFee:  btcToSats(replacing.Fees.Base),
+		ReplacedTxid:  replacedTxid,
+	}); err != nil {
+		return err
+	}
+	if err := q.SetMempoolTransactionReplacedBy(ctx, db.SetMempoolTransactionReplacedByParams{
+		ReplacedBy: &replacingTxid,
+		Txid:       replacedTxid,
+	}); err != nil {
+		return err
+	}
+	return q.DeleteMempoolSpendsByTxids(ctx, []Bytes{replacedTxid})
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+)
+
+// DB is what the store needs from the database, it is satisfied by *pgxpool.Pool and *pgx.Conn
+type DB interface {
+	db.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Fence is called in a write transaction right before it commits and fails when the
+// caller may no longer write, see leader.Elector.Fence and leader.XactFence
+type Fence func(ctx context.Context, q *db.Queries) error
+
+var _ DB = (*pgxpool.Pool)(nil)
+var _ DB = (*pgx.Conn)(nil)
+
+// NewPool creates a traced connection pool, maxConns <= 0 keeps the pgxpool default.
+// Connections are opened lazily, so the database does not have to be up yet.
+func NewPool(ctx context.Context, connString string, maxConns int32) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	if maxConns > 0 {
+		config.MaxConns = maxConns
+	}
+	config.ConnConfig.Tracer = tracing.DBTracer{}
+	return pgxpool.NewWithConfig(ctx, config)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RewindToHeight removes the blocks above height together with their transactions and
+// spaces actions, and moves every component that went past height back to it.
+// With orphan set the blocks are kept and marked as orphans, the way a reorg leaves them.
+// Call it with the queries of a transaction, height -1 empties the chain.
+func RewindToHeight(ctx context.Context, q *db.Queries, height int32, orphan bool) (err error) {
+	ctx, span := tracing.Start(ctx, "store.RewindToHeight", attribute.Int("block.height", int(height)), attribute.Bool("orphan", orphan))
+	defer func() { tracing.End(span, err) }()
+
+	if height < -1 {
+		return fmt.Errorf("invalid rewind height %d", height)
+	}
+
+	var hash *Bytes
+	if height >= 0 {
+		h, err := q.GetBlockHashByHeight(ctx, height)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("no block stored at height %d", height)
+		}
+		if err != nil {
+			return err
+		}
+		hash = &h
+	}
+
+	if orphan {
+		if err := q.SetOrphanAfterHeight(ctx, height); err != nil {
+			return err
+		}
+		if err := q.SetNegativeHeightToOrphans(ctx); err != nil {
+			return err
+		}
+	} else {
+		// transactions, vmetaouts and spends cascade with the blocks
+		if err := q.DeleteBlocksAfterHeight(ctx, height); err != nil {
+			return err
+		}
+	}
+
+	states, err := q.GetIndexerStates(ctx)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		if state.Height > height {
+			if err := UpdateState(ctx, q, state.Component, height, hash); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
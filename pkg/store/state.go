@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// components tracked in the indexer_state table
+const (
+	ComponentBlocks      = "blocks"
+	ComponentSpaces      = "spaces"
+	ComponentMempool     = "mempool"
+	ComponentRollouts    = "rollouts"
+	ComponentRootAnchors = "root_anchors"
+	ComponentBackfill    = "backfill"
+	ComponentPopulate    = "populate"
+	ComponentVerify      = "verify"
+)
+
+// SchemaVersion is the latest migration the code is written against, bump it with every migration
+const SchemaVersion = 20261019200000
+
+// IndexerVersion defaults to the vcs revision of the build,
+// it can be overridden with -ldflags "-X github.com/spacesprotocol/explorer-indexer/pkg/store.IndexerVersion=..."
+var IndexerVersion = buildRevision()
+
+func buildRevision() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+		if info.Main.Version != "" {
+			return info.Main.Version
+		}
+	}
+	return "dev"
+}
+
+// GetState returns the recorded progress of a component, nil if it never recorded any
+func GetState(ctx context.Context, q *db.Queries, component string) (*db.IndexerState, error) {
+	state, err := q.GetIndexerState(ctx, component)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// UpdateState records the last height and block processed by a component, keeping its target height.
+// Call it with the queries of the transaction that did the work.
+func UpdateState(ctx context.Context, q *db.Queries, component string, height int32, hash *Bytes) error {
+	return updateState(ctx, q, component, height, hash, pgtype.Int4{})
+}
+
+// UpdateRangeState records the progress of a component working through blocks up to targetHeight
+func UpdateRangeState(ctx context.Context, q *db.Queries, component string, height int32, hash *Bytes, targetHeight int32) error {
+	return updateState(ctx, q, component, height, hash, pgtype.Int4{Int32: targetHeight, Valid: true})
+}
+
+func updateState(ctx context.Context, q *db.Queries, component string, height int32, hash *Bytes, targetHeight pgtype.Int4) error {
+	return q.UpsertIndexerState(ctx, db.UpsertIndexerStateParams{
+		Component:      component,
+		Height:         height,
+		BlockHash:      hash,
+		TargetHeight:   targetHeight,
+		SchemaVersion:  SchemaVersion,
+		IndexerVersion: IndexerVersion,
+	})
+}
+
+// CopyState records the blocks progress as the progress of another component,
+// for components like the mempool that follow the synced tip
+func CopyState(ctx context.Context, q *db.Queries, component string) error {
+	blocks, err := GetState(ctx, q, ComponentBlocks)
+	if err != nil {
+		return err
+	}
+	if blocks == nil {
+		return UpdateState(ctx, q, component, -1, nil)
+	}
+	return UpdateState(ctx, q, component, blocks.Height, blocks.BlockHash)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -13,12 +12,14 @@ import (
 
 	"github.com/jinzhu/copier"
 	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/logging"
+	"github.com/spacesprotocol/explorer-indexer/pkg/metrics"
 	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
 	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-const deadbeefString = "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
-
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -26,22 +27,59 @@ func max(a, b int) int {
 	return b
 }
 
-func StoreSpacesTransactions(txs []node.MetaTransaction, blockHash Bytes, sqlTx pgx.Tx) (pgx.Tx, error) {
+// StoreSpacesTransactions writes the spaces actions of a block with one COPY, replacing the ones
+// a previous attempt stored for the block so that it can be stored again
+func StoreSpacesTransactions(ctx context.Context, txs []node.MetaTransaction, blockHash Bytes, sqlTx pgx.Tx) (_ pgx.Tx, err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreSpacesTransactions", attribute.Int("spaces.txs", len(txs)))
+	defer func() { tracing.End(span, err) }()
+
+	batch := make([]db.InsertBatchVMetaOutsParams, 0, len(txs))
 	for _, tx := range txs {
-		sqlTx, err := StoreSpacesTransaction(tx, blockHash, sqlTx)
-		if err != nil {
+		batch = append(batch, prepareVMetaOuts(tx, blockHash)...)
+	}
+
+	q := db.New(sqlTx)
+	if err := q.DeleteVMetaOutsByBlockHash(ctx, blockHash); err != nil {
+		return sqlTx, err
+	}
+	if len(batch) == 0 {
+		return sqlTx, nil
+	}
+	rowsAffected, err := q.InsertBatchVMetaOuts(ctx, batch)
+	if err != nil {
+		return sqlTx, fmt.Errorf("batch insert vmetaouts: %w", err)
+	}
+	span.SetAttributes(attribute.Int64("spaces.vmetaouts", rowsAffected))
+	return sqlTx, nil
+}
+
+// StoreMempoolSpacesTransaction stores the spaces outputs of an unconfirmed tx
+func StoreMempoolSpacesTransaction(ctx context.Context, tx node.MetaTransaction, sqlTx pgx.Tx) (_ pgx.Tx, err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreMempoolSpacesTransaction", attribute.String("tx.id", tx.TxID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	q := db.New(sqlTx)
+	vmetaouts := prepareVMetaOuts(tx, nil)
+	for _, vmet := range vmetaouts {
+		params := db.InsertMempoolVMetaOutParams{}
+		copier.Copy(&params, &vmet)
+		if err := q.InsertMempoolVMetaOut(ctx, params); err != nil {
 			return sqlTx, err
 		}
 	}
 	return sqlTx, nil
 }
 
-func StoreSpacesTransaction(tx node.MetaTransaction, blockHash Bytes, sqlTx pgx.Tx) (pgx.Tx, error) {
-	q := db.New(sqlTx)
+// prepareVMetaOuts maps the creates, updates and rejected spends of a spaces tx to vmetaouts rows,
+// keyed by the output or input they describe
+func prepareVMetaOuts(tx node.MetaTransaction, blockHash Bytes) []db.InsertBatchVMetaOutsParams {
+	vmetaouts := make([]db.InsertBatchVMetaOutsParams, 0, len(tx.Creates)+len(tx.Updates)+len(tx.Spends))
 	for _, create := range tx.Creates {
-		vmet := db.InsertVMetaOutParams{
+		vmet := db.InsertBatchVMetaOutsParams{
 			BlockHash:     blockHash,
 			Txid:          tx.TxID,
+			Kind:          db.VmetaoutKindOUTPUT,
+			N:             pgtype.Int4{Int32: int32(create.N), Valid: true},
 			Value:         pgtype.Int8{Int64: int64(create.Value), Valid: true},
 			Scriptpubkey:  &create.ScriptPubKey,
 			OutpointTxid:  &tx.TxID,
@@ -62,35 +100,7 @@ func StoreSpacesTransaction(tx node.MetaTransaction, blockHash Bytes, sqlTx pgx.
 		}
 
 		if create.Covenant.Type != "" {
-			switch strings.ToUpper(create.Covenant.Type) {
-			case "BID":
-				vmet.Action = db.NullCovenantAction{
-					CovenantAction: db.CovenantActionBID,
-					Valid:          true,
-				}
-			case "RESERVE":
-				vmet.Action = db.NullCovenantAction{
-					CovenantAction: db.CovenantActionRESERVE,
-					Valid:          true,
-				}
-			case "TRANSFER":
-				vmet.Action = db.NullCovenantAction{
-					CovenantAction: db.CovenantActionTRANSFER,
-					Valid:          true,
-				}
-			case "ROLLOUT":
-				vmet.Action = db.NullCovenantAction{
-					CovenantAction: db.CovenantActionROLLOUT,
-					Valid:          true,
-				}
-			case "REVOKE":
-				vmet.Action = db.NullCovenantAction{
-					CovenantAction: db.CovenantActionREVOKE,
-					Valid:          true,
-				}
-			default:
-				return sqlTx, fmt.Errorf("unknown covenant action: %s", create.Covenant.Type)
-			}
+			vmet.Action, vmet.RawAction = mapCovenantAction(create.Covenant.Type)
 
 			if create.Covenant.BurnIncrement != nil {
 				vmet.BurnIncrement = pgtype.Int8{Int64: int64(*create.Covenant.BurnIncrement), Valid: true}
@@ -111,17 +121,19 @@ func StoreSpacesTransaction(tx node.MetaTransaction, blockHash Bytes, sqlTx pgx.
 			if create.Covenant.Signature != nil {
 				vmet.Signature = &create.Covenant.Signature
 			}
-		}
 
-		if err := q.InsertVMetaOut(context.Background(), vmet); err != nil {
-			return sqlTx, err
+			vmet.Data, vmet.DataDecoded, vmet.DataFormat = covenantData(create.Covenant.Data)
 		}
+
+		vmetaouts = append(vmetaouts, vmet)
 	}
 
 	for _, update := range tx.Updates {
-		vmet := db.InsertVMetaOutParams{
+		vmet := db.InsertBatchVMetaOutsParams{
 			BlockHash:     blockHash,
 			Txid:          tx.TxID,
+			Kind:          db.VmetaoutKindOUTPUT,
+			N:             pgtype.Int4{Int32: int32(update.Output.N), Valid: true},
 			Value:         pgtype.Int8{Int64: int64(update.Output.Value), Valid: true},
 			Scriptpubkey:  &update.Output.ScriptPubKey,
 			OutpointTxid:  &update.Output.TxID,
@@ -149,35 +161,7 @@ func StoreSpacesTransaction(tx node.MetaTransaction, blockHash Bytes, sqlTx pgx.
 				}
 			}
 		}
-		switch strings.ToUpper(update.Type) {
-		case "BID":
-			vmet.Action = db.NullCovenantAction{
-				CovenantAction: db.CovenantActionBID,
-				Valid:          true,
-			}
-		case "RESERVE":
-			vmet.Action = db.NullCovenantAction{
-				CovenantAction: db.CovenantActionRESERVE,
-				Valid:          true,
-			}
-		case "TRANSFER":
-			vmet.Action = db.NullCovenantAction{
-				CovenantAction: db.CovenantActionTRANSFER,
-				Valid:          true,
-			}
-		case "ROLLOUT":
-			vmet.Action = db.NullCovenantAction{
-				CovenantAction: db.CovenantActionROLLOUT,
-				Valid:          true,
-			}
-		case "REVOKE":
-			vmet.Action = db.NullCovenantAction{
-				CovenantAction: db.CovenantActionREVOKE,
-				Valid:          true,
-			}
-		default:
-			return sqlTx, fmt.Errorf("unknown covenant action: %s", update.Type)
-		}
+		vmet.Action, vmet.RawAction = mapCovenantAction(update.Type)
 		covenant := update.Output.Covenant
 		if covenant.BurnIncrement != nil {
 			vmet.BurnIncrement = pgtype.Int8{
@@ -211,16 +195,18 @@ func StoreSpacesTransaction(tx node.MetaTransaction, blockHash Bytes, sqlTx pgx.
 			vmet.Signature = &covenant.Signature
 		}
 
-		if err := q.InsertVMetaOut(context.Background(), vmet); err != nil {
-			return sqlTx, err
-		}
+		vmet.Data, vmet.DataDecoded, vmet.DataFormat = covenantData(covenant.Data)
+
+		vmetaouts = append(vmetaouts, vmet)
 
 	}
 
 	for _, spend := range tx.Spends {
-		vmet := db.InsertVMetaOutParams{
+		vmet := db.InsertBatchVMetaOutsParams{
 			BlockHash: blockHash,
 			Txid:      tx.TxID,
+			Kind:      db.VmetaoutKindSPEND,
+			N:         pgtype.Int4{Int32: int32(spend.N), Valid: true},
 		}
 
 		if spend.ScriptError != nil {
@@ -242,29 +228,28 @@ func StoreSpacesTransaction(tx node.MetaTransaction, blockHash Bytes, sqlTx pgx.
 				vmet.ScriptError = pgtype.Text{String: spend.ScriptError.Reason, Valid: true}
 			}
 
-			//TODO handle script error types gracefully
-			if strings.ToUpper(spend.ScriptError.Type) == "REJECT" {
-				vmet.Action = db.NullCovenantAction{CovenantAction: db.CovenantActionREJECT, Valid: true}
-			} else {
-				vmet.Action = db.NullCovenantAction{CovenantAction: db.CovenantActionREJECT, Valid: true}
-				vmet.ScriptError = pgtype.Text{String: spend.ScriptError.Reason + string(spend.ScriptError.Type), Valid: true}
+			// every script error rejects the spend, its type tells why
+			vmet.Action = db.NullCovenantAction{CovenantAction: db.CovenantActionREJECT, Valid: true}
+			if spend.ScriptError.Type != "" {
+				vmet.ScriptErrorType = pgtype.Text{String: strings.ToLower(spend.ScriptError.Type), Valid: true}
 			}
 
-			if err := q.InsertVMetaOut(context.Background(), vmet); err != nil {
-				return sqlTx, err
-			}
+			vmetaouts = append(vmetaouts, vmet)
 		}
 
 	}
 
-	return sqlTx, nil
+	return vmetaouts
 }
 
-func StoreBitcoinBlock(block *node.Block, tx pgx.Tx) (pgx.Tx, error) {
+func StoreBitcoinBlock(ctx context.Context, block *node.Block, tx pgx.Tx) (_ pgx.Tx, err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreBitcoinBlock", blockAttributes(block)...)
+	defer func() { tracing.End(span, err) }()
+
 	q := db.New(tx)
 	blockParams := db.UpsertBlockParams{}
 	copier.Copy(&blockParams, &block)
-	wasInserted, err := q.UpsertBlock(context.Background(), blockParams)
+	wasInserted, err := q.UpsertBlock(ctx, blockParams)
 	if err != nil {
 		return tx, err
 	}
@@ -272,40 +257,64 @@ func StoreBitcoinBlock(block *node.Block, tx pgx.Tx) (pgx.Tx, error) {
 		// Prepare all transactions for batch insert
 		batchParams := prepareBatchTransactions(block.Transactions, &blockParams.Hash)
 
-		log.Printf("Batch inserting %d transactions for block", len(batchParams))
+		logger := logging.Component("store").With("height", block.Height, "block_hash", block.Hash)
+		logger.Debug("batch inserting block transactions", "txs", len(batchParams))
 
 		// Batch insert all transactions at once using PostgreSQL COPY protocol
-		rowsAffected, err := q.InsertBatchTransactions(context.Background(), batchParams)
+		rowsAffected, err := q.InsertBatchTransactions(ctx, batchParams)
 		if err != nil {
 			return tx, fmt.Errorf("batch insert transactions: %w", err)
 		}
 
-		log.Printf("Successfully inserted %d transactions", rowsAffected)
+		logger.Debug("inserted block transactions", "rows", rowsAffected)
+	}
+
+	if err := promoteMempoolTransactions(ctx, q, block); err != nil {
+		return tx, err
 	}
 	return tx, nil
 }
 
-func storeTransactionBase(q *db.Queries, transaction *node.Transaction, blockHash *Bytes, txIndex *int32) error {
+func blockAttributes(block *node.Block) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int("block.height", int(block.Height)),
+		attribute.String("block.hash", block.Hash.String()),
+		attribute.Int("block.txs", len(block.Transactions)),
+	}
+}
+
+// promoteMempoolTransactions drops the mempool copies of the block's transactions,
+// their confirmed rows are written from the block itself within the same db transaction
+func promoteMempoolTransactions(ctx context.Context, q *db.Queries, block *node.Block) (err error) {
+	ctx, span := tracing.Start(ctx, "store.promoteMempoolTransactions")
+	defer func() { tracing.End(span, err) }()
+
+	txids := make([]Bytes, 0, len(block.Transactions))
+	for _, transaction := range block.Transactions {
+		txids = append(txids, transaction.Txid)
+	}
+	// the txs a confirmed tx replaced can't confirm anymore
+	if err := q.DeleteMempoolTransactionsReplacedBy(ctx, txids); err != nil {
+		return err
+	}
+	if err := q.DeleteMempoolTransactionsByTxids(ctx, txids); err != nil {
+		return err
+	}
+	return q.DeleteMempoolEntriesByTxids(ctx, txids)
+}
+
+func storeTransactionBase(ctx context.Context, q *db.Queries, transaction *node.Transaction, blockHash *Bytes, txIndex *int32) error {
 	// Calculate aggregates for all transactions
 	inputCount, outputCount, totalOutputValue := calculateAggregates(transaction)
 
-	if blockHash.String() != deadbeefString {
-		params := db.InsertTransactionParams{}
-		copier.Copy(&params, transaction)
-		params.BlockHash = *blockHash
-		params.Index = *txIndex
-		params.InputCount = inputCount
-		params.OutputCount = outputCount
-		params.TotalOutputValue = totalOutputValue
-		return q.InsertTransaction(context.Background(), params)
-	}
-	params := db.InsertMempoolTransactionParams{}
+	params := db.InsertTransactionParams{}
 	copier.Copy(&params, transaction)
 	params.BlockHash = *blockHash
+	params.Index = *txIndex
 	params.InputCount = inputCount
 	params.OutputCount = outputCount
 	params.TotalOutputValue = totalOutputValue
-	return q.InsertMempoolTransaction(context.Background(), params)
+	return q.InsertTransaction(ctx, params)
 }
 
 // calculateAggregates computes input/output counts and total output value
@@ -352,11 +361,15 @@ func prepareBatchTransactions(transactions []node.Transaction, blockHash *Bytes)
 }
 
 // detects chain split (reorganization) and
-// returns the height and blockhash of the last block that is identical in the db and in the node
-func GetSyncedHead(ctx context.Context, pg *pgx.Conn, bc *node.BitcoinClient) (int32, *Bytes, error) {
+// returns the height and blockhash of the last block that is identical in the db and in the node.
+// It only reads, the sync leader moves the db back to the head with RewindToSyncedHead.
+func GetSyncedHead(ctx context.Context, pg DB, bc *node.BitcoinClient) (_ int32, _ *Bytes, err error) {
+	ctx, span := tracing.Start(ctx, "store.GetSyncedHead")
+	defer func() { tracing.End(span, err) }()
+
 	q := db.New(pg)
-	//takes last block from the DB
-	height, err := q.GetBlocksMaxHeight(ctx)
+	//takes the last synced block, databases without a recorded state fall back to the highest block
+	height, err := SyncedHeight(ctx, q)
 	if err != nil {
 		return -1, nil, err
 	}
@@ -375,13 +388,6 @@ func GetSyncedHead(ctx context.Context, pg *pgx.Conn, bc *node.BitcoinClient) (i
 		// nodeHash *bytes
 		// dbHash Bytes
 		if bytes.Equal(dbHash, *nodeHash) {
-			//marking all the blocks in the DB after the sycned height as orphans
-			if err := q.SetOrphanAfterHeight(ctx, height); err != nil {
-				return -1, nil, err
-			}
-			if err := q.SetNegativeHeightToOrphans(ctx); err != nil {
-				return -1, nil, err
-			}
 			return height, &dbHash, nil
 		}
 		height -= 1
@@ -389,22 +395,100 @@ func GetSyncedHead(ctx context.Context, pg *pgx.Conn, bc *node.BitcoinClient) (i
 	return -1, nil, nil
 }
 
-func StoreBlock(ctx context.Context, pg *pgx.Conn, block *node.Block, sc *node.SpacesClient, activationBlock int32) error {
+// SyncedHeight is the height of the blocks checkpoint, the highest stored block without one
+func SyncedHeight(ctx context.Context, q *db.Queries) (int32, error) {
+	state, err := GetState(ctx, q, ComponentBlocks)
+	if err != nil {
+		return -1, err
+	}
+	if state != nil {
+		return state.Height, nil
+	}
+	return q.GetBlocksMaxHeight(ctx)
+}
+
+// RewindToSyncedHead orphans the blocks after the synced head found by GetSyncedHead and moves
+// the blocks and spaces progress back to it in one db transaction, committed only if fence passes
+func RewindToSyncedHead(ctx context.Context, pg DB, height int32, hash *Bytes, fence Fence) error {
+	ctx, tx, err := tracing.BeginTx(ctx, pg, "RewindToSyncedHead")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(tx)
+	var reorgDepth int32
+	//marking all the blocks in the DB after the sycned height as orphans
+	if err := q.SetOrphanAfterHeight(ctx, height); err != nil {
+		return err
+	}
+	if err := q.SetNegativeHeightToOrphans(ctx); err != nil {
+		return err
+	}
+	for _, component := range []string{ComponentBlocks, ComponentSpaces} {
+		state, err := GetState(ctx, q, component)
+		if err != nil {
+			return err
+		}
+		if state == nil || state.Height > height {
+			if err := UpdateState(ctx, q, component, height, hash); err != nil {
+				return err
+			}
+		}
+		if component == ComponentBlocks && state != nil && state.Height > height {
+			reorgDepth = state.Height - height
+		}
+	}
+	if fence != nil {
+		if err := fence(ctx, q); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if reorgDepth > 0 {
+		metrics.Reorgs.Inc()
+		metrics.ReorgDepth.Observe(float64(reorgDepth))
+	}
+	return nil
+}
+
+// StoreBlock stores a block and its spaces transactions, recording the progress of
+// the component storing it in the same db transaction. Only the blocks component
+// follows the chain tip and also records the spaces progress.
+// The transaction commits only if fence passes, a nil fence is for a single writer like the tests.
+func StoreBlock(ctx context.Context, pg DB, block *node.Block, sc *node.SpacesClient, activationBlock int32, component string, fence Fence) error {
+	return storeBlock(ctx, pg, block, sc, activationBlock, component, pgtype.Int4{}, fence)
+}
+
+// StoreRangeBlock stores a block like StoreBlock for a component working towards targetHeight,
+// the target is recorded along with the progress
+func StoreRangeBlock(ctx context.Context, pg DB, block *node.Block, sc *node.SpacesClient, activationBlock int32, component string, targetHeight int32, fence Fence) error {
+	return storeBlock(ctx, pg, block, sc, activationBlock, component, pgtype.Int4{Int32: targetHeight, Valid: true}, fence)
+}
+
+func storeBlock(ctx context.Context, pg DB, block *node.Block, sc *node.SpacesClient, activationBlock int32, component string, targetHeight pgtype.Int4, fence Fence) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreBlock", blockAttributes(block)...)
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.Component("store").With("height", block.Height, "block_hash", block.Hash)
 	totalStart := time.Now()
 	defer func() {
-		log.Printf("Total block %d processing time: %s", block.Height, time.Since(totalStart))
+		logger.Info("block processed", "duration", time.Since(totalStart))
+		metrics.BlockStoreDuration.Observe(time.Since(totalStart).Seconds())
 	}()
 
-	log.Printf("trying to store block #%d", block.Height)
+	logger.Debug("storing block")
 
-	tx, err := pg.BeginTx(ctx, pgx.TxOptions{})
+	ctx, tx, err := tracing.BeginTx(ctx, pg, "StoreBlock")
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Store Bitcoin block
-	tx, err = StoreBitcoinBlock(block, tx)
+	tx, err = StoreBitcoinBlock(ctx, block, tx)
 	if err != nil {
 		return err
 	}
@@ -415,18 +499,51 @@ func StoreBlock(ctx context.Context, pg *pgx.Conn, block *node.Block, sc *node.S
 			return err
 		}
 
-		tx, err = StoreSpacesTransactions(spacesBlock.Transactions, block.Hash, tx)
+		tx, err = StoreSpacesTransactions(ctx, spacesBlock.Transactions, block.Hash, tx)
 		if err != nil {
 			return err
 		}
 	}
 
+	q := db.New(tx)
+	if err := updateState(ctx, q, component, block.Height, &block.Hash, targetHeight); err != nil {
+		return err
+	}
+	if component == ComponentBlocks && block.Height >= activationBlock {
+		if err := UpdateState(ctx, q, ComponentSpaces, block.Height, &block.Hash); err != nil {
+			return err
+		}
+	}
+	if fence != nil {
+		if err := fence(ctx, q); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit(ctx)
 }
 
-func StoreTransaction(q *db.Queries, transaction *node.Transaction, blockHash *Bytes, txIndex *int32) error {
-	if err := storeTransactionBase(q, transaction, blockHash, txIndex); err != nil {
+func StoreTransaction(ctx context.Context, q *db.Queries, transaction *node.Transaction, blockHash *Bytes, txIndex *int32) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreTransaction", attribute.String("tx.id", transaction.Txid.String()))
+	defer func() { tracing.End(span, err) }()
+
+	if err := storeTransactionBase(ctx, q, transaction, blockHash, txIndex); err != nil {
 		return err
 	}
 	return nil
 }
+
+// StoreMempoolTransaction stores an unconfirmed tx, it is removed again once it confirms or leaves the mempool
+func StoreMempoolTransaction(ctx context.Context, q *db.Queries, transaction *node.Transaction) (err error) {
+	ctx, span := tracing.Start(ctx, "store.StoreMempoolTransaction", attribute.String("tx.id", transaction.Txid.String()))
+	defer func() { tracing.End(span, err) }()
+
+	inputCount, outputCount, totalOutputValue := calculateAggregates(transaction)
+
+	params := db.InsertMempoolTransactionParams{}
+	copier.Copy(&params, transaction)
+	params.InputCount = inputCount
+	params.OutputCount = outputCount
+	params.TotalOutputValue = totalOutputValue
+	return q.InsertMempoolTransaction(ctx, params)
+}
@@ -0,0 +1,406 @@
+package store_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/spacesim"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store/storetest"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+const schemaDir = "../../sql/schema"
+
+// TestStore runs every scenario against its own database and chain on a throwaway postgres,
+// it is skipped when the postgres server binaries are not installed
+func TestStore(t *testing.T) {
+	pg, err := storetest.StartPostgres(context.Background())
+	if errors.Is(err, storetest.ErrPostgresNotFound) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pg.Stop() })
+
+	scenarios := []struct {
+		name string
+		run  func(t *testing.T, env *storetest.Env)
+	}{
+		{"linear sync", linearSync},
+		{"one block reorg", reorg(1)},
+		{"deep reorg", reorg(12)},
+		{"reorg of spaces actions", spacesReorg},
+		{"duplicate blocks", duplicateBlocks},
+		{"updates of outputs at the same n", sameOutputN},
+		{"mempool add remove confirm", mempoolLifecycle},
+		{"covenant actions", covenantActions},
+		{"auction", auction},
+		{"covenant data", covenantData},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			env, err := storetest.NewEnv(context.Background(), pg, scenario.name, schemaDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(env.Close)
+			scenario.run(t, env)
+		})
+	}
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func linearSync(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	env.Chain.MineN(20)
+	check(t, env.SyncBlocks(ctx))
+	check(t, env.CheckChain(ctx))
+
+	env.Chain.Mine(env.Chain.NewTx(), env.Chain.NewTx())
+	env.Chain.MineN(4)
+	check(t, env.SyncBlocks(ctx))
+	check(t, env.CheckChain(ctx))
+	check(t, env.CheckOrphans(ctx, 0))
+}
+
+// reorg replaces the top depth blocks with depth+1 new ones
+func reorg(depth int) func(t *testing.T, env *storetest.Env) {
+	return func(t *testing.T, env *storetest.Env) {
+		ctx := context.Background()
+		env.Chain.MineN(2 * depth)
+		check(t, env.SyncBlocks(ctx))
+
+		env.Chain.Reorg(depth)
+		env.Chain.Mine(env.Chain.NewTx())
+		env.Chain.MineN(depth)
+		check(t, env.SyncBlocks(ctx))
+		check(t, env.CheckChain(ctx))
+		check(t, env.CheckOrphans(ctx, depth))
+	}
+}
+
+// the spaces actions of orphaned blocks stop counting towards the space state
+func spacesReorg(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	env.Chain.MineN(3)
+	env.Chain.MineMeta([]node.MetaTransaction{bidMeta("kept", 1000)}, env.Chain.NewTx())
+	env.Chain.MineMeta([]node.MetaTransaction{bidMeta("dropped", 2000)}, env.Chain.NewTx())
+	check(t, env.SyncBlocks(ctx))
+
+	env.Chain.Reorg(1)
+	env.Chain.MineN(2)
+	check(t, env.SyncBlocks(ctx))
+	check(t, env.CheckChain(ctx))
+
+	states, err := env.Queries().GetLatestSpaceStates(ctx, []string{"kept", "dropped"})
+	check(t, err)
+	if len(states) != 1 || states[0].Name.String != "kept" {
+		t.Fatalf("expected only the space of the kept block, got %d states", len(states))
+	}
+}
+
+// storing a block again leaves the db as it was, spaces actions included
+func duplicateBlocks(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	env.Chain.Mine(env.Chain.NewTx(), env.Chain.NewTx())
+	env.Chain.MineMeta([]node.MetaTransaction{bidMeta("twice", 1000), rejectMeta("twice")}, env.Chain.NewTx(), env.Chain.NewTx())
+	env.Chain.MineN(2)
+	check(t, env.SyncBlocks(ctx))
+
+	for _, height := range []int32{1, 2, 2, 4} {
+		storeAgain(t, env, env.Chain.BlockAt(height))
+	}
+	check(t, env.CheckChain(ctx))
+	check(t, env.CheckRows(ctx, 2, "vmetaouts", "name = $1", "twice"))
+	check(t, env.CheckOrphans(ctx, 0))
+}
+
+// a tx updating output 0 of two different txs stores both updates, also when stored again
+func sameOutputN(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	meta := bidMeta("first", 1000)
+	meta.Updates[0].Output.TxID = env.Chain.NewTx().Txid
+	second := bidMeta("second", 2000).Updates[0]
+	second.Output.TxID = env.Chain.NewTx().Txid
+	meta.Updates = append(meta.Updates, second)
+	env.Chain.MineMeta([]node.MetaTransaction{meta}, env.Chain.NewTx())
+	check(t, env.SyncBlocks(ctx))
+
+	storeAgain(t, env, env.Chain.Tip())
+	check(t, env.CheckChain(ctx))
+	check(t, env.CheckRows(ctx, 2, "vmetaouts", "kind = 'OUTPUT' AND n = 0"))
+	for _, name := range []string{"first", "second"} {
+		check(t, env.CheckRows(ctx, 1, "vmetaouts", "name = $1", name))
+	}
+}
+
+func mempoolLifecycle(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	env.Chain.MineN(2)
+	check(t, env.SyncBlocks(ctx))
+
+	bid := bidMeta("pending", 5000)
+	bid.Spends = scriptErrorMeta("pending", "Expired", 1).Spends
+	confirmed := env.Chain.AddMempoolTx(env.Chain.NewTx(), &bid)
+	evicted := env.Chain.AddMempoolTx(env.Chain.NewTx(), nil)
+	check(t, env.SyncMempool(ctx))
+	check(t, env.CheckMempool(ctx, confirmed.Txid, evicted.Txid))
+	check(t, env.CheckRows(ctx, 1, "mempool_vmetaouts", "name = $1 AND kind = 'OUTPUT' AND n = 0", "pending"))
+	// the mempool keeps the rejected input and the error type apart from the reason too
+	check(t, env.CheckRows(ctx, 1, "mempool_vmetaouts", "name = $1 AND kind = 'SPEND' AND n = 1 AND script_error_type = 'expired' AND script_error = $2",
+		"pending", "scripted rejection"))
+
+	env.Chain.RemoveMempoolTx(evicted.Txid)
+	check(t, env.SyncMempool(ctx))
+	check(t, env.CheckMempool(ctx, confirmed.Txid))
+	check(t, env.CheckRows(ctx, 0, "mempool_entries", "txid = $1", evicted.Txid))
+
+	env.Chain.MineMempool()
+	check(t, env.SyncBlocks(ctx))
+	check(t, env.SyncMempool(ctx))
+	check(t, env.CheckMempool(ctx))
+	check(t, env.CheckRows(ctx, 0, "mempool_vmetaouts", ""))
+	check(t, env.CheckRows(ctx, 1, "vmetaouts", "name = $1 AND action = 'BID'", "pending"))
+	check(t, env.CheckChain(ctx))
+}
+
+// every covenant type the spaced node reports, as a created output and as an update
+var covenantTypes = []string{"OPEN", "RESERVE", "BID", "ROLLOUT", "REGISTER", "TRANSFER", "REVOKE"}
+
+func covenantActions(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	var metas []node.MetaTransaction
+	var txs []node.Transaction
+	for _, covenantType := range covenantTypes {
+		txs = append(txs, env.Chain.NewTx())
+		metas = append(metas, createMeta("create-"+covenantType, covenantType))
+		txs = append(txs, env.Chain.NewTx())
+		metas = append(metas, updateMeta("update-"+covenantType, covenantType))
+	}
+	txs = append(txs, env.Chain.NewTx())
+	metas = append(metas, rejectMeta("rejected"))
+	txs = append(txs, env.Chain.NewTx())
+	metas = append(metas, scriptErrorMeta("expired", "Expired", 1))
+	txs = append(txs, env.Chain.NewTx())
+	metas = append(metas, updateMeta("unknown", "future"))
+	env.Chain.MineMeta(metas, txs...)
+
+	check(t, env.SyncBlocks(ctx))
+	check(t, env.CheckChain(ctx))
+
+	for _, covenantType := range covenantTypes {
+		check(t, env.CheckRows(ctx, 2, "vmetaouts", "action = $1::covenant_action", covenantType))
+		for _, name := range []string{"create-" + covenantType, "update-" + covenantType} {
+			check(t, env.CheckRows(ctx, 1, "vmetaouts", "name = $1 AND action = $2::covenant_action", name, covenantType))
+		}
+	}
+	check(t, env.CheckRows(ctx, 1, "vmetaouts", "name = $1 AND action = 'REJECT' AND script_error_type = 'reject'", "rejected"))
+	// the error type is kept apart from the reason, with the input it rejected
+	check(t, env.CheckRows(ctx, 1, "vmetaouts", "name = $1 AND script_error_type = 'expired' AND script_error = $2 AND kind = 'SPEND' AND n = 1",
+		"expired", "scripted rejection"))
+	// a type the indexer does not know is kept instead of failing the block
+	check(t, env.CheckRows(ctx, 1, "vmetaouts", "name = $1 AND action = 'UNKNOWN' AND raw_action = $2", "unknown", "future"))
+}
+
+// an auction played by the simulator, with a reorg in the middle of it
+func auction(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	sim := spacesim.New(env.Chain, spacesim.DefaultParams)
+	alice, bob, carol := Bytes{0x51, 0x01}, Bytes{0x51, 0x02}, Bytes{0x51, 0x03}
+	steps := []struct {
+		name string
+		play func() error
+	}{
+		{"open", func() error {
+			if err := sim.Open("@alpha", 1000, alice); err != nil {
+				return err
+			}
+			return sim.Open("@beta", 500, bob)
+		}},
+		{"rollout", func() error {
+			if err := sim.Rollout("@alpha", 1); err != nil {
+				return err
+			}
+			return sim.Bid("@beta", 600, carol)
+		}},
+		{"outbid", func() error { return sim.Bid("@alpha", 2000, bob) }},
+		{"reorg", func() error {
+			// drops the outbid, another one lands on the new chain
+			sim.Reorg(1)
+			return sim.Bid("@alpha", 1500, carol)
+		}},
+		{"low bid", func() error {
+			if err := sim.Bid("@alpha", 1200, alice); !errors.Is(err, spacesim.ErrRejected) {
+				t.Fatalf("expected the low bid to be rejected, got %v", err)
+			}
+			return nil
+		}},
+		{"claim", func() error {
+			space, _ := sim.Space("@alpha")
+			sim.MineUntil(*space.ClaimHeight - 1)
+			return sim.Claim("@alpha")
+		}},
+		{"transfer", func() error { return sim.Transfer("@alpha", alice) }},
+		{"renew", func() error { return sim.Renew("@alpha") }},
+		{"revoke", func() error { return sim.Revoke("@beta", "bid psbt spent") }},
+	}
+	for _, step := range steps {
+		if err := step.play(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		sim.Mine()
+		check(t, env.SyncBlocks(ctx))
+		check(t, env.CheckChain(ctx))
+		if err := env.CheckSpaces(ctx, sim); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+
+	check(t, env.CheckOrphans(ctx, 1))
+	check(t, env.CheckRows(ctx, 1, "vmetaouts", "action = 'REJECT'"))
+}
+
+// transfers set, replace and clear the data of a space, each one kept in its history
+func covenantData(t *testing.T, env *storetest.Env) {
+	ctx := context.Background()
+	payloads := []interface{}{
+		hex.EncodeToString([]byte(`{"records":["a"]}`)),
+		hex.EncodeToString([]byte("hello")),
+		"00ff",
+		nil,
+		"not hex",
+		7,
+		hex.EncodeToString([]byte("kept")),
+	}
+	for _, payload := range payloads {
+		meta := updateMeta("data", "transfer")
+		meta.Updates[0].Output.Covenant.Data = payload
+		env.Chain.MineMeta([]node.MetaTransaction{meta}, env.Chain.NewTx())
+	}
+	// an action other than a transfer leaves the data as it is
+	env.Chain.MineMeta([]node.MetaTransaction{updateMeta("data", "register")}, env.Chain.NewTx())
+	check(t, env.SyncBlocks(ctx))
+
+	history, err := env.Queries().GetSpaceDataHistory(ctx, pgtype.Text{String: "data", Valid: true})
+	check(t, err)
+	// newest first, payloads that are not hex are kept as reported instead of as data
+	expected := []struct {
+		data    string
+		decoded string
+		format  string
+	}{
+		{"6b657074", "kept", "text"},
+		{"", "7", "invalid"},
+		{"", `"not hex"`, "invalid"},
+		{"", "", ""},
+		{"00ff", "", ""},
+		{"68656c6c6f", "hello", "text"},
+		{hex.EncodeToString([]byte(`{"records":["a"]}`)), `{"records":["a"]}`, "json"},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("%d data history rows, expected %d", len(history), len(expected))
+	}
+	for i, row := range history {
+		var data Bytes
+		if row.Data != nil {
+			data = *row.Data
+		}
+		if data.String() != expected[i].data || row.DataDecoded.String != expected[i].decoded || row.DataFormat.String != expected[i].format {
+			t.Errorf("data history row %d is %s %q (%s), expected %s %q (%s)", i, data, row.DataDecoded.String, row.DataFormat.String,
+				expected[i].data, expected[i].decoded, expected[i].format)
+		}
+	}
+
+	states, err := env.Queries().GetLatestSpaceStates(ctx, []string{"data"})
+	check(t, err)
+	if len(states) != 1 || states[0].Action.CovenantAction != db.CovenantActionREGISTER {
+		t.Fatal("expected the register to be the latest state of the space")
+	}
+	if states[0].Data == nil || string(*states[0].Data) != "kept" {
+		t.Errorf("latest state has data %v, expected the data of the last transfer", states[0].Data)
+	}
+}
+
+func storeAgain(t *testing.T, env *storetest.Env, block *node.Block) {
+	t.Helper()
+	if block == nil {
+		t.Fatal("block missing from the chain")
+	}
+	full, err := env.BC.GetBlock(context.Background(), block.Hash.String())
+	check(t, err)
+	check(t, store.StoreBlock(context.Background(), env.Pool, full, env.SC, env.ActivationBlock, store.ComponentBlocks, nil))
+}
+
+func bidMeta(name string, value int) node.MetaTransaction {
+	burn := value
+	return node.MetaTransaction{
+		Updates: []node.UpdateMeta{{
+			Type: "bid",
+			Output: node.OutputMeta{
+				N:            0,
+				Name:         "@" + name,
+				Value:        value,
+				ScriptPubKey: Bytes{0x51},
+				Covenant:     node.Covenant{Type: "bid", BurnIncrement: &burn, TotalBurned: &burn, Signature: Bytes{0x01}},
+			},
+		}},
+	}
+}
+
+func createMeta(name string, covenantType string) node.MetaTransaction {
+	claimHeight := 100
+	return node.MetaTransaction{
+		Creates: []node.CreateMeta{{
+			N:            0,
+			Name:         "@" + name,
+			Value:        1000,
+			ScriptPubKey: Bytes{0x51},
+			Covenant:     node.Covenant{Type: covenantType, ClaimHeight: &claimHeight},
+		}},
+	}
+}
+
+func updateMeta(name string, covenantType string) node.MetaTransaction {
+	expireHeight := 200
+	return node.MetaTransaction{
+		Updates: []node.UpdateMeta{{
+			Type:     covenantType,
+			Priority: 1,
+			Reason:   "scripted",
+			Output: node.OutputMeta{
+				N:            0,
+				Name:         "@" + name,
+				Value:        1000,
+				ScriptPubKey: Bytes{0x51},
+				Covenant:     node.Covenant{Type: covenantType, ExpireHeight: &expireHeight},
+			},
+		}},
+	}
+}
+
+func rejectMeta(name string) node.MetaTransaction {
+	return scriptErrorMeta(name, "reject", 0)
+}
+
+// scriptErrorMeta is a spend of input n that spaced rejected with an error of the type
+func scriptErrorMeta(name string, errorType string, n int) node.MetaTransaction {
+	meta := node.MetaTransaction{}
+	meta.Spends = append(meta.Spends, struct {
+		N           int               `json:"n"`
+		ScriptError *node.ScriptError `json:"script_error,omitempty"`
+	}{N: n, ScriptError: &node.ScriptError{Type: errorType, Name: "@" + name, Reason: "scripted rejection"}})
+	return meta
+}
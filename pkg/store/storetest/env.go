@@ -0,0 +1,281 @@
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/mempool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node/nodetest"
+	"github.com/spacesprotocol/explorer-indexer/pkg/spacesim"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+)
+
+// confirmation targets of the fee snapshots stored by the mempool passes
+var feeTargets = []int{1, 6}
+
+// Env is a migrated database and the fake nodes of one scenario
+type Env struct {
+	Pool     *pgxpool.Pool
+	Chain    *nodetest.Chain
+	Bitcoind *nodetest.Server
+	Spaced   *nodetest.Server
+	BC       *node.BitcoinClient
+	SC       *node.SpacesClient
+	Mempool  *mempool.Syncer
+
+	// shared by the blocks and the mempool passes, like in the sync command
+	chainMu sync.Mutex
+
+	// blocks below it are stored without their spaces transactions
+	ActivationBlock int32
+}
+
+// NewEnv creates the database of a scenario and fake nodes serving a chain holding the genesis block
+func NewEnv(ctx context.Context, pg *Postgres, name string, schemaDir string) (*Env, error) {
+	pool, err := pg.CreateDatabase(ctx, databaseName(name), schemaDir)
+	if err != nil {
+		return nil, err
+	}
+	chain := nodetest.NewChain()
+	bitcoind := nodetest.NewBitcoind(chain)
+	spaced := nodetest.NewSpaced(chain)
+	env := &Env{
+		Pool:     pool,
+		Chain:    chain,
+		Bitcoind: bitcoind,
+		Spaced:   spaced,
+		BC:       nodetest.NewBitcoinClient(bitcoind),
+		SC:       nodetest.NewSpacesClient(spaced),
+	}
+	env.Mempool = mempool.New(pool, env.BC, env.SC, &env.chainMu, feeTargets, time.Hour, 0)
+	return env, nil
+}
+
+func (env *Env) Close() {
+	env.Bitcoind.Close()
+	env.Spaced.Close()
+	env.Pool.Close()
+}
+
+func (env *Env) Queries() *db.Queries {
+	return db.New(env.Pool)
+}
+
+// SyncBlocks follows the chain the way the blocks worker of the sync command does:
+// it finds the synced head, rolling back a reorg, and stores the blocks after it
+func (env *Env) SyncBlocks(ctx context.Context) error {
+	height, head, err := store.GetSyncedHead(ctx, env.Pool, env.BC)
+	if err != nil {
+		return err
+	}
+	if head != nil {
+		if err := store.RewindToSyncedHead(ctx, env.Pool, height, head, nil); err != nil {
+			return err
+		}
+	}
+	hash, err := env.BC.GetBlockHash(ctx, int(height+1))
+	if err != nil {
+		if strings.Contains(err.Error(), "Block height out of range") {
+			return nil
+		}
+		return err
+	}
+	for hash != nil && len(*hash) > 0 {
+		block, err := env.BC.GetBlock(ctx, hash.String())
+		if err != nil {
+			return err
+		}
+		env.chainMu.Lock()
+		err = store.StoreBlock(ctx, env.Pool, block, env.SC, env.ActivationBlock, store.ComponentBlocks, nil)
+		env.chainMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("store block %d: %w", block.Height, err)
+		}
+		hash = &block.NextBlockHash
+	}
+	return nil
+}
+
+// SyncMempool runs a mempool pass of the sync command
+func (env *Env) SyncMempool(ctx context.Context) error {
+	return env.Mempool.Sync(ctx)
+}
+
+// CheckChain verifies that the non orphan blocks in the db are exactly the active chain of the node,
+// each with all of its transactions, and that the blocks state points at the tip
+func (env *Env) CheckChain(ctx context.Context) error {
+	tip := env.Chain.Height()
+	var dbBlocks int32
+	if err := env.Pool.QueryRow(ctx, "SELECT COUNT(*)::integer FROM blocks WHERE NOT orphan").Scan(&dbBlocks); err != nil {
+		return err
+	}
+	if dbBlocks != tip+1 {
+		return fmt.Errorf("%d non orphan blocks in the db, the chain has %d", dbBlocks, tip+1)
+	}
+
+	q := env.Queries()
+	for height := int32(0); height <= tip; height++ {
+		block := env.Chain.BlockAt(height)
+		hash, err := q.GetBlockHashByHeight(ctx, height)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", height, err)
+		}
+		if !bytes.Equal(hash, block.Hash) {
+			return fmt.Errorf("block %d is %s in the db, %s in the chain", height, hash, block.Hash)
+		}
+		var txs int
+		if err := env.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM transactions WHERE block_hash = $1", block.Hash).Scan(&txs); err != nil {
+			return err
+		}
+		if txs != len(block.Transactions) {
+			return fmt.Errorf("block %d has %d txs in the db, %d in the chain", height, txs, len(block.Transactions))
+		}
+	}
+
+	state, err := store.GetState(ctx, q, store.ComponentBlocks)
+	if err != nil {
+		return err
+	}
+	if tip > 0 && (state == nil || state.Height != tip) {
+		return fmt.Errorf("blocks state is %v, the tip is %d", state, tip)
+	}
+	return nil
+}
+
+// CheckOrphans verifies how many blocks are kept as orphans
+func (env *Env) CheckOrphans(ctx context.Context, expected int) error {
+	var orphans int
+	if err := env.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM blocks WHERE orphan").Scan(&orphans); err != nil {
+		return err
+	}
+	if orphans != expected {
+		return fmt.Errorf("%d orphan blocks, expected %d", orphans, expected)
+	}
+	return nil
+}
+
+// CheckMempool verifies the txids stored in the db mempool
+func (env *Env) CheckMempool(ctx context.Context, expected ...Bytes) error {
+	txids, err := env.Queries().GetMempoolTxids(ctx)
+	if err != nil {
+		return err
+	}
+	if len(txids) != len(expected) {
+		return fmt.Errorf("%d txs in the db mempool, expected %d", len(txids), len(expected))
+	}
+	stored := make(map[string]bool, len(txids))
+	for _, txid := range txids {
+		stored[txid.String()] = true
+	}
+	for _, txid := range expected {
+		if !stored[txid.String()] {
+			return fmt.Errorf("tx %s missing from the db mempool", txid)
+		}
+	}
+	return nil
+}
+
+// CountRows counts the rows of a table matching a condition on its columns, e.g.
+// CountRows(ctx, "vmetaouts", "action = $1", "BID")
+func (env *Env) CountRows(ctx context.Context, table string, where string, args ...interface{}) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", table)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	var count int
+	err := env.Pool.QueryRow(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// CheckRows verifies the result of CountRows
+func (env *Env) CheckRows(ctx context.Context, expected int, table string, where string, args ...interface{}) error {
+	count, err := env.CountRows(ctx, table, where, args...)
+	if err != nil {
+		return err
+	}
+	if count != expected {
+		return fmt.Errorf("%d rows in %s where %s %v, expected %d", count, table, where, args, expected)
+	}
+	return nil
+}
+
+// CheckSpaces verifies that the latest state of every simulated space in the db
+// is the one the simulator expects
+func (env *Env) CheckSpaces(ctx context.Context, sim *spacesim.Simulator) error {
+	expected := sim.Spaces()
+	names := make([]string, 0, len(expected))
+	for _, space := range expected {
+		names = append(names, space.Name)
+	}
+	rows, err := env.Queries().GetLatestSpaceStates(ctx, names)
+	if err != nil {
+		return err
+	}
+	if len(rows) != len(expected) {
+		return fmt.Errorf("%d spaces in the db, expected %d", len(rows), len(expected))
+	}
+
+	byName := make(map[string]db.GetLatestSpaceStatesRow, len(rows))
+	for _, row := range rows {
+		byName[row.Name.String] = row
+	}
+	for _, space := range expected {
+		row, exists := byName[space.Name]
+		if !exists {
+			return fmt.Errorf("@%s missing from the db", space.Name)
+		}
+		var mismatches []string
+		check := func(field string, got interface{}, want interface{}) {
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				mismatches = append(mismatches, fmt.Sprintf("%s is %v, expected %v", field, got, want))
+			}
+		}
+		check("action", row.Action.CovenantAction, space.Action)
+		check("value", row.Value.Int64, space.Value)
+		check("owner", deref(row.Scriptpubkey), space.Owner)
+		check("priority", int8Value(row.Priority), space.Priority)
+		check("reason", row.Reason.String, space.Reason)
+		check("burn increment", int8Ptr(row.BurnIncrement), intValue(space.BurnIncrement))
+		check("total burned", int8Ptr(row.TotalBurned), intValue(space.TotalBurned))
+		check("claim height", int8Ptr(row.ClaimHeight), intValue(space.ClaimHeight))
+		check("expire height", int8Ptr(row.ExpireHeight), intValue(space.ExpireHeight))
+		if len(mismatches) > 0 {
+			return fmt.Errorf("@%s: %s", space.Name, strings.Join(mismatches, ", "))
+		}
+	}
+	return nil
+}
+
+func deref(b *Bytes) Bytes {
+	if b == nil {
+		return nil
+	}
+	return *b
+}
+
+func int8Value(i pgtype.Int8) int {
+	return int(i.Int64)
+}
+
+func int8Ptr(i pgtype.Int8) string {
+	if !i.Valid {
+		return "null"
+	}
+	return fmt.Sprint(i.Int64)
+}
+
+func intValue(i *int) string {
+	if i == nil {
+		return "null"
+	}
+	return fmt.Sprint(*i)
+}
@@ -0,0 +1,100 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+)
+
+// Migrate applies the goose Up sections of the migrations in dir, in version order,
+// each one in its own transaction like goose does
+func Migrate(ctx context.Context, pg store.DB, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations in %s", dir)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		up := upSection(string(migration))
+		if strings.Contains(string(migration), "-- +goose NO TRANSACTION") {
+			// CREATE INDEX CONCURRENTLY can't even run in the implicit transaction of a multi statement query
+			for _, statement := range splitStatements(up) {
+				if _, err := pg.Exec(ctx, statement); err != nil {
+					return fmt.Errorf("migration %s: %w", filepath.Base(file), err)
+				}
+			}
+			continue
+		}
+
+		tx, err := pg.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		// without arguments the statements go through the simple protocol, several at once
+		if _, err := tx.Exec(ctx, up); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("migration %s: %w", filepath.Base(file), err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upSection returns the statements between -- +goose Up and -- +goose Down
+func upSection(migration string) string {
+	var up strings.Builder
+	inUp := false
+	for _, line := range strings.Split(migration, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			inUp = true
+			continue
+		case "-- +goose Down":
+			inUp = false
+			continue
+		}
+		if inUp {
+			up.WriteString(line)
+			up.WriteString("\n")
+		}
+	}
+	return up.String()
+}
+
+// splitStatements splits sql at the semicolons ending a line, the way goose does outside of
+// StatementBegin/StatementEnd blocks
+func splitStatements(sql string) []string {
+	var statements []string
+	var statement strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") && statement.Len() == 0 {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, statement.String())
+			statement.Reset()
+		}
+	}
+	if strings.TrimSpace(statement.String()) != "" {
+		statements = append(statements, statement.String())
+	}
+	return statements
+}
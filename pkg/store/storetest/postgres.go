@@ -0,0 +1,200 @@
+// Package storetest runs the store against a throwaway local Postgres, started from an
+// initdb'd data directory, with the migrations in sql/schema applied to every database.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+)
+
+const startTimeout = 30 * time.Second
+
+// ErrPostgresNotFound is returned by StartPostgres when the postgres server binaries are missing
+var ErrPostgresNotFound = errors.New("postgres server binaries not found, install postgres or set PG_BIN")
+
+// Postgres is a local server owning a temporary data directory
+type Postgres struct {
+	Dir  string
+	Port int
+
+	cmd *exec.Cmd
+}
+
+// StartPostgres initdb's a temporary data directory and starts a server listening
+// on a unix socket inside it. The binaries are looked up in PG_BIN, then in PATH.
+func StartPostgres(ctx context.Context) (*Postgres, error) {
+	initdb, err := pgBinary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	postgres, err := pgBinary("postgres")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "storetest-")
+	if err != nil {
+		return nil, err
+	}
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	pg := &Postgres{Dir: dir, Port: port}
+
+	data := filepath.Join(dir, "data")
+	out, err := exec.CommandContext(ctx, initdb, "-D", data, "-U", "postgres", "-A", "trust", "--no-sync", "-E", "UTF8").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	log, err := os.Create(filepath.Join(dir, "postgres.log"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	defer log.Close()
+	pg.cmd = exec.Command(postgres,
+		"-D", data,
+		"-p", fmt.Sprint(port),
+		"-k", dir,
+		"-c", "listen_addresses=",
+		"-c", "fsync=off",
+		"-c", "synchronous_commit=off",
+		"-c", "full_page_writes=off",
+	)
+	pg.cmd.Stdout = log
+	pg.cmd.Stderr = log
+	if err := pg.cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	if err := pg.waitReady(ctx); err != nil {
+		pg.Stop()
+		return nil, err
+	}
+	return pg, nil
+}
+
+// ConnString returns the connection string of a database on the server
+func (pg *Postgres) ConnString(database string) string {
+	return fmt.Sprintf("host=%s port=%d user=postgres dbname=%s sslmode=disable", pg.Dir, pg.Port, database)
+}
+
+// CreateDatabase creates an empty database with the migrations in schemaDir applied
+// and returns a pool connected to it
+func (pg *Postgres) CreateDatabase(ctx context.Context, name string, schemaDir string) (*pgxpool.Pool, error) {
+	admin, err := store.NewPool(ctx, pg.ConnString("postgres"), 1)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+	if _, err := admin.Exec(ctx, fmt.Sprintf("CREATE DATABASE %q", name)); err != nil {
+		return nil, fmt.Errorf("create database %s: %w", name, err)
+	}
+
+	pool, err := store.NewPool(ctx, pg.ConnString(name), 4)
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(ctx, pool, schemaDir); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Stop shuts the server down and removes its data directory
+func (pg *Postgres) Stop() error {
+	if pg.cmd != nil && pg.cmd.Process != nil {
+		// SIGINT is the fast shutdown of postgres
+		pg.cmd.Process.Signal(os.Interrupt)
+		done := make(chan error, 1)
+		go func() { done <- pg.cmd.Wait() }()
+		select {
+		case <-done:
+		case <-time.After(startTimeout):
+			pg.cmd.Process.Kill()
+			<-done
+		}
+	}
+	return os.RemoveAll(pg.Dir)
+}
+
+func (pg *Postgres) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	for {
+		pool, err := store.NewPool(ctx, pg.ConnString("postgres"), 1)
+		if err == nil {
+			err = pool.Ping(ctx)
+			pool.Close()
+			if err == nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			logs, _ := os.ReadFile(filepath.Join(pg.Dir, "postgres.log"))
+			return fmt.Errorf("postgres did not start: %w: %s", err, logs)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// pgBinary finds a postgres binary, debian keeps them out of PATH in /usr/lib/postgresql/<version>/bin
+func pgBinary(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%s: %w", path, ErrPostgresNotFound)
+		}
+		return path, nil
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql/*/bin", name))
+	if len(matches) > 0 {
+		sort.Strings(matches)
+		return matches[len(matches)-1], nil
+	}
+	return "", fmt.Errorf("%s: %w", name, ErrPostgresNotFound)
+}
+
+// freePort returns a port nothing listens on, it names the unix socket of the server
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// databaseName turns a scenario name into a database name
+func databaseName(name string) string {
+	return "storetest_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, name)
+}
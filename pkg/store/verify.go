@@ -0,0 +1,310 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/leader"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	. "github.com/spacesprotocol/explorer-indexer/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// checks reported by VerifyBlocks and VerifyRootAnchors
+const (
+	CheckMissingBlock    = "missing_block"
+	CheckDuplicateHeight = "duplicate_height"
+	CheckBlockHash       = "block_hash"
+	CheckTxids           = "txids"
+	CheckVMetaOuts       = "vmetaouts"
+	CheckRootAnchor      = "root_anchor"
+)
+
+// Issue is a difference between the db and the nodes at a height
+type Issue struct {
+	Height    int32  `json:"height"`
+	Check     string `json:"check"`
+	BlockHash string `json:"block_hash,omitempty"`
+	Detail    string `json:"detail"`
+	Repaired  bool   `json:"repaired"`
+}
+
+// VerifyReport lists the issues found between two heights
+type VerifyReport struct {
+	FromHeight    int32   `json:"from_height"`
+	ToHeight      int32   `json:"to_height"`
+	BlocksChecked int     `json:"blocks_checked"`
+	Issues        []Issue `json:"issues"`
+}
+
+// Consistent tells whether every issue found was repaired
+func (report *VerifyReport) Consistent() bool {
+	for _, issue := range report.Issues {
+		if !issue.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyBlocks compares the blocks stored between two heights with the nodes, the spaces
+// transactions of the blocks from activationBlock on are compared with their block meta
+func VerifyBlocks(ctx context.Context, pg DB, bc *node.BitcoinClient, sc *node.SpacesClient, fromHeight int32, toHeight int32, activationBlock int32) (_ *VerifyReport, err error) {
+	ctx, span := tracing.Start(ctx, "store.VerifyBlocks", attribute.Int("from_height", int(fromHeight)), attribute.Int("to_height", int(toHeight)))
+	defer func() { tracing.End(span, err) }()
+
+	report := &VerifyReport{FromHeight: fromHeight, ToHeight: toHeight, Issues: []Issue{}}
+	q := db.New(pg)
+	rows, err := q.GetBlocksInRange(ctx, db.GetBlocksInRangeParams{FromHeight: fromHeight, ToHeight: toHeight})
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[int32][]Bytes, len(rows))
+	for _, row := range rows {
+		stored[row.Height] = append(stored[row.Height], row.Hash)
+	}
+
+	for height := fromHeight; height <= toHeight; height++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		nodeHash, err := bc.GetBlockHash(ctx, int(height))
+		if err != nil {
+			return report, err
+		}
+		report.BlocksChecked++
+
+		hashes := stored[height]
+		if len(hashes) == 0 {
+			report.add(height, CheckMissingBlock, *nodeHash, "no block stored at this height")
+			continue
+		}
+		if len(hashes) > 1 {
+			report.add(height, CheckDuplicateHeight, *nodeHash, fmt.Sprintf("%d non orphan blocks at this height", len(hashes)))
+		}
+		found := false
+		for _, hash := range hashes {
+			if bytes.Equal(hash, *nodeHash) {
+				found = true
+			}
+		}
+		if !found {
+			report.add(height, CheckBlockHash, *nodeHash, fmt.Sprintf("db has %s, node has %s", hashes[0], nodeHash))
+			continue
+		}
+
+		block, err := bc.GetBlock(ctx, nodeHash.String())
+		if err != nil {
+			return report, err
+		}
+		if detail, err := compareTxids(ctx, q, block); err != nil {
+			return report, err
+		} else if detail != "" {
+			report.add(height, CheckTxids, block.Hash, detail)
+		}
+
+		if height < activationBlock {
+			continue
+		}
+		spacesBlock, err := sc.GetBlockMeta(ctx, block.Hash.String())
+		if err != nil {
+			return report, err
+		}
+		if detail, err := compareVMetaOuts(ctx, q, block.Hash, spacesBlock.Transactions); err != nil {
+			return report, err
+		} else if detail != "" {
+			report.add(height, CheckVMetaOuts, block.Hash, detail)
+		}
+	}
+	return report, nil
+}
+
+// VerifyRootAnchors compares the root anchors of the blocks between two heights with the spaces node
+func VerifyRootAnchors(ctx context.Context, pg DB, sc *node.SpacesClient, report *VerifyReport) (err error) {
+	ctx, span := tracing.Start(ctx, "store.VerifyRootAnchors")
+	defer func() { tracing.End(span, err) }()
+
+	anchors, err := sc.GetRootAnchors(ctx)
+	if err != nil {
+		return err
+	}
+	q := db.New(pg)
+	rows, err := q.GetBlocksInRange(ctx, db.GetBlocksInRangeParams{FromHeight: report.FromHeight, ToHeight: report.ToHeight})
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]*Bytes, len(rows))
+	for _, row := range rows {
+		stored[row.Hash.String()] = row.RootAnchor
+	}
+
+	for _, anchor := range anchors {
+		height := int32(anchor.Block.Height)
+		if height < report.FromHeight || height > report.ToHeight {
+			continue
+		}
+		root, exists := stored[anchor.Block.Hash.String()]
+		switch {
+		case !exists:
+			// reported by the block checks already
+		case root == nil:
+			report.add(height, CheckRootAnchor, anchor.Block.Hash, fmt.Sprintf("root anchor %s missing", anchor.Root))
+		case !bytes.Equal(*root, anchor.Root):
+			report.add(height, CheckRootAnchor, anchor.Block.Hash, fmt.Sprintf("db has root anchor %s, node has %s", *root, anchor.Root))
+		}
+	}
+	return nil
+}
+
+func (report *VerifyReport) add(height int32, check string, hash Bytes, detail string) {
+	report.Issues = append(report.Issues, Issue{Height: height, Check: check, BlockHash: hash.String(), Detail: detail})
+}
+
+func compareTxids(ctx context.Context, q *db.Queries, block *node.Block) (string, error) {
+	txids, err := q.GetTxidsByBlockHash(ctx, block.Hash)
+	if err != nil {
+		return "", err
+	}
+	if len(txids) != len(block.Transactions) {
+		return fmt.Sprintf("db has %d txs, node has %d", len(txids), len(block.Transactions)), nil
+	}
+	for i, tx := range block.Transactions {
+		if !bytes.Equal(txids[i], tx.Txid) {
+			return fmt.Sprintf("tx %d is %s in the db, %s in the node", i, txids[i], tx.Txid), nil
+		}
+	}
+	return "", nil
+}
+
+// compareVMetaOuts compares the vmetaouts of a block by txid, name and action with the rows its block meta maps to
+func compareVMetaOuts(ctx context.Context, q *db.Queries, blockHash Bytes, txs []node.MetaTransaction) (string, error) {
+	rows, err := q.GetVMetaOutKeysByBlockHash(ctx, blockHash)
+	if err != nil {
+		return "", err
+	}
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[vmetaoutKey(row.Txid, row.Name.String, string(row.Action.CovenantAction))]++
+	}
+	expected := 0
+	for _, tx := range txs {
+		vmetaouts := prepareVMetaOuts(tx, blockHash)
+		for _, vmet := range vmetaouts {
+			counts[vmetaoutKey(vmet.Txid, vmet.Name.String, string(vmet.Action.CovenantAction))]--
+			expected++
+		}
+	}
+
+	var differences []string
+	for key, count := range counts {
+		if count > 0 {
+			differences = append(differences, fmt.Sprintf("%d extra %s", count, key))
+		} else if count < 0 {
+			differences = append(differences, fmt.Sprintf("%d missing %s", -count, key))
+		}
+	}
+	if len(differences) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("db has %d vmetaouts, node meta has %d: %s", len(rows), expected, strings.Join(differences, ", ")), nil
+}
+
+func vmetaoutKey(txid Bytes, name string, action string) string {
+	return fmt.Sprintf("%s/%s/%s", txid, name, action)
+}
+
+// RepairBlock stores the node's block at its height again in one db transaction: other blocks
+// at that height become orphans and the block's own rows are rewritten from the nodes.
+// It refuses to run while a sync leader holds leaderLockKey, and keeps one from starting meanwhile.
+func RepairBlock(ctx context.Context, pg DB, block *node.Block, sc *node.SpacesClient, activationBlock int32, leaderLockKey int64) (err error) {
+	ctx, span := tracing.Start(ctx, "store.RepairBlock", blockAttributes(block)...)
+	defer func() { tracing.End(span, err) }()
+
+	ctx, tx, err := tracing.BeginTx(ctx, pg, "RepairBlock")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(tx)
+	if err := leader.LockXact(ctx, q, leaderLockKey); err != nil {
+		return err
+	}
+	if err := q.OrphanBlocksAtHeight(ctx, db.OrphanBlocksAtHeightParams{Height: block.Height, Hash: block.Hash}); err != nil {
+		return err
+	}
+	// transactions and vmetaouts cascade, the root anchor is restored by the root anchors check
+	if err := q.DeleteBlockByHash(ctx, block.Hash); err != nil {
+		return err
+	}
+	if tx, err = StoreBitcoinBlock(ctx, block, tx); err != nil {
+		return err
+	}
+	if block.Height >= activationBlock {
+		spacesBlock, err := sc.GetBlockMeta(ctx, block.Hash.String())
+		if err != nil {
+			return err
+		}
+		if tx, err = StoreSpacesTransactions(ctx, spacesBlock.Transactions, block.Hash, tx); err != nil {
+			return err
+		}
+	}
+	if err := UpdateState(ctx, db.New(tx), ComponentVerify, block.Height, &block.Hash); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RepairBlocks stores again the node's block at every height with a block issue and marks the issues repaired,
+// run it before VerifyRootAnchors since the repaired blocks lose their root anchor
+func RepairBlocks(ctx context.Context, pg DB, bc *node.BitcoinClient, sc *node.SpacesClient, report *VerifyReport, activationBlock int32, leaderLockKey int64) error {
+	repaired := make(map[int32]bool)
+	for i, issue := range report.Issues {
+		if issue.Repaired || issue.Check == CheckRootAnchor {
+			continue
+		}
+		if !repaired[issue.Height] {
+			block, err := bc.GetBlock(ctx, issue.BlockHash)
+			if err != nil {
+				return err
+			}
+			if err := RepairBlock(ctx, pg, block, sc, activationBlock, leaderLockKey); err != nil {
+				return fmt.Errorf("repair block %d: %w", issue.Height, err)
+			}
+			repaired[issue.Height] = true
+		}
+		report.Issues[i].Repaired = true
+	}
+	return nil
+}
+
+// RepairRootAnchors stores the root anchors the spaces node reports for the blocks with a root anchor issue
+func RepairRootAnchors(ctx context.Context, pg DB, sc *node.SpacesClient, report *VerifyReport) error {
+	anchors, err := sc.GetRootAnchors(ctx)
+	if err != nil {
+		return err
+	}
+	byBlock := make(map[string]*node.RootAnchor, len(anchors))
+	for _, anchor := range anchors {
+		byBlock[anchor.Block.Hash.String()] = anchor
+	}
+	q := db.New(pg)
+	for i, issue := range report.Issues {
+		if issue.Repaired || issue.Check != CheckRootAnchor {
+			continue
+		}
+		anchor, exists := byBlock[issue.BlockHash]
+		if !exists {
+			continue
+		}
+		if err := q.UpdateRootAnchor(ctx, db.UpdateRootAnchorParams{RootAnchor: &anchor.Root, Hash: anchor.Block.Hash}); err != nil {
+			return err
+		}
+		report.Issues[i].Repaired = true
+	}
+	return nil
+}
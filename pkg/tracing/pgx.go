@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DBTracer opens a span for every query and COPY run on a connection,
+// queries generated by sqlc are named after their sqlc name
+type DBTracer struct{}
+
+var _ pgx.QueryTracer = DBTracer{}
+var _ pgx.CopyFromTracer = DBTracer{}
+
+func (DBTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = Start(ctx, "db "+queryName(data.SQL),
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", data.SQL),
+	)
+	return ctx
+}
+
+func (DBTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	End(span, data.Err)
+}
+
+func (DBTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, _ = Start(ctx, "db copy "+strings.Join(data.TableName, "."),
+		attribute.String("db.system", "postgresql"),
+		attribute.StringSlice("db.columns", data.ColumnNames),
+	)
+	return ctx
+}
+
+func (DBTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	End(span, data.Err)
+}
+
+// queryName returns the sqlc name of a query or its first keyword
+func queryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if name, ok := strings.CutPrefix(sql, "-- name: "); ok {
+		if fields := strings.Fields(name); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		return strings.ToLower(fields[0])
+	}
+	return "query"
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// tracedTx ends the transaction span on commit or rollback
+type tracedTx struct {
+	pgx.Tx
+	span trace.Span
+}
+
+// BeginTx begins a db transaction wrapped in a span named after it,
+// queries run with the returned context are children of that span
+func BeginTx(ctx context.Context, pg txBeginner, name string) (context.Context, pgx.Tx, error) {
+	ctx, span := Start(ctx, "db.tx "+name)
+	tx, err := pg.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		End(span, err)
+		return ctx, nil, err
+	}
+	return ctx, &tracedTx{Tx: tx, span: span}, nil
+}
+
+func (tx *tracedTx) Commit(ctx context.Context) error {
+	err := tx.Tx.Commit(ctx)
+	End(tx.span, err)
+	return err
+}
+
+func (tx *tracedTx) Rollback(ctx context.Context) error {
+	err := tx.Tx.Rollback(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		// already committed or rolled back, the span has ended
+		return err
+	}
+	tx.span.SetAttributes(attribute.Bool("db.rolled_back", true))
+	End(tx.span, err)
+	return err
+}
@@ -0,0 +1,170 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/spacesprotocol/explorer-indexer/pkg/db"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node"
+	"github.com/spacesprotocol/explorer-indexer/pkg/node/nodetest"
+	"github.com/spacesprotocol/explorer-indexer/pkg/store"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing"
+	"github.com/spacesprotocol/explorer-indexer/pkg/tracing/tracingtest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var errDB = errors.New("db unavailable")
+
+// failingDB fails every query
+type failingDB struct{}
+
+func (failingDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errDB
+}
+
+func (failingDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, errDB
+}
+
+func (failingDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return nil
+}
+
+func (failingDB) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errDB
+}
+
+// fakeTx only commits and rolls back, a second end reports the tx as closed like pgx does
+type fakeTx struct {
+	pgx.Tx
+	closed bool
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	return tx.Commit(context.Background())
+}
+
+type fakeBeginner struct{}
+
+func (fakeBeginner) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+// span returns the only span recorded with the name
+func span(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	var found []tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			found = append(found, span)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("%d spans named %q, want 1", len(found), name)
+	}
+	return found[0]
+}
+
+func attr(span tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestRpcSpans(t *testing.T) {
+	exporter := tracingtest.Setup()
+	ctx := context.Background()
+	chain := nodetest.NewChain()
+	chain.MineN(2)
+	server := nodetest.NewBitcoind(chain)
+	defer server.Close()
+	bc := nodetest.NewBitcoinClient(server)
+
+	if _, err := bc.GetBlockCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.GetBlockHash(ctx, 100); err == nil {
+		t.Fatal("getblockhash out of range returned no error")
+	}
+
+	count := span(t, exporter, "rpc getblockcount")
+	if method, _ := attr(count, "rpc.method"); method.AsString() != "getblockcount" {
+		t.Errorf("rpc.method = %q, want getblockcount", method.AsString())
+	}
+	if system, _ := attr(count, "rpc.system"); system.AsString() != "jsonrpc" {
+		t.Errorf("rpc.system = %q, want jsonrpc", system.AsString())
+	}
+	if count.Status.Code != codes.Unset {
+		t.Errorf("getblockcount status = %v, want unset", count.Status.Code)
+	}
+	if hash := span(t, exporter, "rpc getblockhash"); hash.Status.Code != codes.Error {
+		t.Errorf("getblockhash status = %v, want error", hash.Status.Code)
+	}
+}
+
+func TestStoreSpans(t *testing.T) {
+	exporter := tracingtest.Setup()
+	entries := map[string]node.MempoolTx{"0101010101010101010101010101010101010101010101010101010101010101": {VSize: 100}}
+
+	err := store.StoreMempoolEntries(context.Background(), db.New(failingDB{}), entries)
+	if !errors.Is(err, errDB) {
+		t.Fatalf("err = %v, want the db error", err)
+	}
+	stored := span(t, exporter, "store.StoreMempoolEntries")
+	if txs, _ := attr(stored, "mempool.txs"); txs.AsInt64() != 1 {
+		t.Errorf("mempool.txs = %d, want 1", txs.AsInt64())
+	}
+	if stored.Status.Code != codes.Error || stored.Status.Description != errDB.Error() {
+		t.Errorf("status = %v %q, want the db error", stored.Status.Code, stored.Status.Description)
+	}
+}
+
+func TestTxSpans(t *testing.T) {
+	tests := []struct {
+		name         string
+		end          func(ctx context.Context, tx pgx.Tx)
+		wantRollback bool
+	}{
+		{"commit", func(ctx context.Context, tx pgx.Tx) {
+			tx.Commit(ctx)
+			// the deferred rollback after a commit leaves the span as it was
+			tx.Rollback(ctx)
+		}, false},
+		{"rollback", func(ctx context.Context, tx pgx.Tx) { tx.Rollback(ctx) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracingtest.Setup()
+			ctx, tx, err := tracing.BeginTx(context.Background(), fakeBeginner{}, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.end(ctx, tx)
+
+			got := span(t, exporter, "db.tx "+tt.name)
+			rolledBack, _ := attr(got, "db.rolled_back")
+			if rolledBack.AsBool() != tt.wantRollback {
+				t.Errorf("db.rolled_back = %v, want %v", rolledBack.AsBool(), tt.wantRollback)
+			}
+			if got.Status.Code != codes.Unset {
+				t.Errorf("status = %v, want unset", got.Status.Code)
+			}
+		})
+	}
+}
@@ -69,3 +69,13 @@ UPDATE blocks SET orphan = true WHERE height > $1;
 
 -- name: SetNegativeHeightToOrphans :exec
 UPDATE blocks SET height = -2 WHERE orphan = true;
+
+-- name: CountRowsAfterHeight :one
+SELECT
+  (SELECT COUNT(*) FROM blocks WHERE blocks.height > @height::integer)::integer AS blocks,
+  (SELECT COUNT(*) FROM transactions
+    INNER JOIN blocks ON (blocks.hash = transactions.block_hash)
+    WHERE blocks.height > @height::integer)::integer AS transactions,
+  (SELECT COUNT(*) FROM vmetaouts
+    INNER JOIN blocks ON (blocks.hash = vmetaouts.block_hash)
+    WHERE blocks.height > @height::integer)::integer AS vmetaouts;
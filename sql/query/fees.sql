@@ -0,0 +1,63 @@
+-- name: InsertFeeSnapshot :one
+INSERT INTO fee_snapshots (
+    tx_count,
+    total_vsize,
+    total_fees
+)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: InsertFeeHistogramBuckets :copyfrom
+INSERT INTO fee_histogram_buckets (
+    snapshot_id,
+    fee_rate_min,
+    fee_rate_max,
+    tx_count,
+    total_vsize,
+    total_fees
+)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: InsertFeeEstimate :exec
+INSERT INTO fee_estimates (
+    snapshot_id,
+    conf_target,
+    fee_rate,
+    blocks
+)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteFeeSnapshotsBefore :exec
+DELETE FROM fee_snapshots
+WHERE taken_at < $1;
+
+-- name: GetLatestFeeEstimates :many
+SELECT fee_estimates.*, fee_snapshots.taken_at
+FROM fee_estimates
+    INNER JOIN fee_snapshots ON (fee_estimates.snapshot_id = fee_snapshots.id)
+WHERE fee_estimates.snapshot_id = (
+    SELECT MAX(snapshot_id) FROM fee_estimates
+)
+ORDER BY fee_estimates.conf_target;
+
+-- name: GetLatestFeeHistogram :many
+SELECT *
+FROM fee_histogram_buckets
+WHERE snapshot_id = (
+    SELECT MAX(id) FROM fee_snapshots
+)
+ORDER BY fee_rate_min;
+
+-- name: GetFeeSnapshots :many
+SELECT *
+FROM fee_snapshots
+WHERE taken_at >= $1 AND taken_at < $2
+ORDER BY taken_at;
+
+-- name: GetFeeEstimatesHistory :many
+SELECT fee_snapshots.taken_at, fee_estimates.fee_rate, fee_estimates.blocks
+FROM fee_estimates
+    INNER JOIN fee_snapshots ON (fee_estimates.snapshot_id = fee_snapshots.id)
+WHERE fee_estimates.conf_target = $1
+AND fee_snapshots.taken_at >= $2
+ORDER BY fee_snapshots.taken_at;
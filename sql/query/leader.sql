@@ -0,0 +1,31 @@
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock(@key::bigint);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock(@key::bigint);
+
+-- name: SetApplicationName :exec
+SELECT set_config('application_name', @name::text, false);
+
+-- name: GetAdvisoryLockHolder :one
+SELECT pg_stat_activity.application_name
+FROM pg_locks
+    INNER JOIN pg_stat_activity ON (pg_locks.pid = pg_stat_activity.pid)
+WHERE pg_locks.locktype = 'advisory'
+AND pg_locks.objsubid = 1
+AND pg_locks.granted
+AND ((pg_locks.classid::bigint << 32) | pg_locks.objid::bigint) = @key::bigint
+LIMIT 1;
+
+-- name: HoldsAdvisoryLock :one
+SELECT EXISTS (
+    SELECT 1 FROM pg_locks
+    WHERE pg_locks.locktype = 'advisory'
+    AND pg_locks.objsubid = 1
+    AND pg_locks.granted
+    AND ((pg_locks.classid::bigint << 32) | pg_locks.objid::bigint) = @key::bigint
+    AND pg_locks.pid = @pid::integer
+);
+
+-- name: TryAdvisoryXactLock :one
+SELECT pg_try_advisory_xact_lock(@key::bigint);
@@ -0,0 +1,153 @@
+-- name: InsertMempoolTransaction :exec
+INSERT INTO mempool_transactions (
+    txid, tx_hash, version, size, vsize, weight, locktime, fee,
+    input_count, output_count, total_output_value
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: GetMempoolTxids :many
+SELECT txid
+FROM mempool_transactions
+WHERE replaced_by IS NULL
+ORDER BY seq;
+
+-- name: GetMempoolTransactions :many
+SELECT *
+FROM mempool_transactions
+WHERE replaced_by IS NULL
+ORDER BY seq
+LIMIT $1 OFFSET $2;
+
+-- name: DeleteMempoolTransactions :exec
+DELETE FROM mempool_transactions;
+
+-- name: DeleteMempoolTransactionByTxid :exec
+DELETE FROM mempool_transactions
+WHERE txid = $1;
+
+-- name: DeleteMempoolTransactionsByTxids :exec
+DELETE FROM mempool_transactions
+WHERE txid = ANY($1::bytea[]);
+
+-- name: DeleteMempoolTransactionsReplacedBy :exec
+WITH RECURSIVE replaced AS (
+    SELECT m.txid
+    FROM mempool_transactions m
+    WHERE m.replaced_by = ANY($1::bytea[])
+    UNION
+    SELECT m.txid
+    FROM mempool_transactions m
+    JOIN replaced ON m.replaced_by = replaced.txid
+)
+DELETE FROM mempool_transactions
+WHERE txid IN (SELECT txid FROM replaced);
+
+-- name: SetMempoolTransactionReplacedBy :exec
+UPDATE mempool_transactions SET replaced_by = $1
+WHERE txid = $2;
+
+-- name: UpsertMempoolEntries :exec
+INSERT INTO mempool_entries (
+    txid,
+    first_seen,
+    vsize,
+    weight,
+    base_fee,
+    modified_fee,
+    fee_rate,
+    ancestor_count,
+    ancestor_size,
+    ancestor_fees,
+    descendant_count,
+    descendant_size,
+    descendant_fees,
+    bip125_replaceable
+)
+SELECT
+    unnest(@txids::bytea[]),
+    unnest(@first_seen::bigint[]),
+    unnest(@vsize::bigint[]),
+    unnest(@weight::bigint[]),
+    unnest(@base_fee::bigint[]),
+    unnest(@modified_fee::bigint[]),
+    unnest(@fee_rate::double precision[]),
+    unnest(@ancestor_count::integer[]),
+    unnest(@ancestor_size::bigint[]),
+    unnest(@ancestor_fees::bigint[]),
+    unnest(@descendant_count::integer[]),
+    unnest(@descendant_size::bigint[]),
+    unnest(@descendant_fees::bigint[]),
+    unnest(@bip125_replaceable::boolean[])
+ON CONFLICT (txid) DO UPDATE
+SET
+    first_seen = LEAST(mempool_entries.first_seen, EXCLUDED.first_seen),
+    vsize = EXCLUDED.vsize,
+    weight = EXCLUDED.weight,
+    base_fee = EXCLUDED.base_fee,
+    modified_fee = EXCLUDED.modified_fee,
+    fee_rate = EXCLUDED.fee_rate,
+    ancestor_count = EXCLUDED.ancestor_count,
+    ancestor_size = EXCLUDED.ancestor_size,
+    ancestor_fees = EXCLUDED.ancestor_fees,
+    descendant_count = EXCLUDED.descendant_count,
+    descendant_size = EXCLUDED.descendant_size,
+    descendant_fees = EXCLUDED.descendant_fees,
+    bip125_replaceable = EXCLUDED.bip125_replaceable,
+    updated_at = now()
+WHERE (
+    mempool_entries.modified_fee,
+    mempool_entries.ancestor_count,
+    mempool_entries.ancestor_fees,
+    mempool_entries.descendant_count,
+    mempool_entries.descendant_fees,
+    mempool_entries.bip125_replaceable
+) IS DISTINCT FROM (
+    EXCLUDED.modified_fee,
+    EXCLUDED.ancestor_count,
+    EXCLUDED.ancestor_fees,
+    EXCLUDED.descendant_count,
+    EXCLUDED.descendant_fees,
+    EXCLUDED.bip125_replaceable
+);
+
+-- name: GetMempoolEntryByTxid :one
+SELECT *
+FROM mempool_entries
+WHERE txid = $1;
+
+-- name: DeleteMempoolEntriesByTxids :exec
+DELETE FROM mempool_entries
+WHERE txid = ANY($1::bytea[]);
+
+-- name: InsertMempoolSpends :exec
+INSERT INTO mempool_spends (txid, prev_txid, prev_index)
+SELECT
+    @txid::bytea,
+    unnest(@prev_txids::bytea[]),
+    unnest(@prev_indexes::integer[]);
+
+-- name: GetMempoolSpendsByTxids :many
+SELECT *
+FROM mempool_spends
+WHERE txid = ANY($1::bytea[]);
+
+-- name: DeleteMempoolSpendsByTxids :exec
+DELETE FROM mempool_spends
+WHERE txid = ANY($1::bytea[]);
+
+-- name: InsertMempoolReplacement :exec
+INSERT INTO mempool_replacements (replaced_txid, replacing_txid, fee_delta)
+SELECT txid, @replacing_txid::bytea, @replacing_fee::bigint - fee
+FROM mempool_transactions
+WHERE txid = @replaced_txid
+ON CONFLICT (replaced_txid, replacing_txid) DO NOTHING;
+
+-- name: GetMempoolReplacementsByTxid :many
+SELECT *
+FROM mempool_replacements
+WHERE replaced_txid = $1 OR replacing_txid = $1
+ORDER BY replaced_at;
+
+-- name: CountMempoolTransactions :one
+SELECT COUNT(*)::integer
+FROM mempool_transactions
+WHERE replaced_by IS NULL;
@@ -1,6 +1,18 @@
--- name: InsertVMetaOut :exec
-INSERT INTO vmetaouts (
-    block_hash,
+-- name: InsertRollout :exec
+INSERT INTO rollouts (
+    name,
+    bid,
+    target
+)
+VALUES ($1, $2, $3);
+
+
+-- name: DeleteRollouts :exec
+DELETE FROM rollouts; 
+
+
+-- name: InsertMempoolVMetaOut :exec
+INSERT INTO mempool_vmetaouts (
     txid,
     priority,
     name,
@@ -15,30 +27,180 @@ INSERT INTO vmetaouts (
     script_error,
     reason,
     outpoint_txid,
-    outpoint_index
+    outpoint_index,
+    raw_action,
+    script_error_type,
+    data,
+    data_decoded,
+    data_format,
+    kind,
+    n
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22);
 
 
--- name: InsertRollout :exec
-INSERT INTO rollouts (
+-- name: InsertBatchVMetaOuts :copyfrom
+INSERT INTO vmetaouts (
+    block_hash,
+    txid,
+    kind,
+    n,
+    priority,
     name,
-    bid,
-    target
+    value,
+    scriptPubKey,
+    action,
+    burn_increment,
+    signature,
+    total_burned,
+    claim_height,
+    expire_height,
+    script_error,
+    reason,
+    outpoint_txid,
+    outpoint_index,
+    raw_action,
+    script_error_type,
+    data,
+    data_decoded,
+    data_format
 )
-VALUES ($1, $2, $3);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23);
 
 
--- name: DeleteRollouts :exec
-DELETE FROM rollouts; 
+-- name: DeleteVMetaOutsByBlockHash :exec
+DELETE FROM vmetaouts WHERE block_hash = $1;
 
 
 -- name: DeleteMempoolVmetaouts :exec
-DELETE FROM vmetaouts
-WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+DELETE FROM mempool_vmetaouts;
 
 
 -- name: DeleteMempoolVmetaoutsByTxid :exec
-DELETE FROM vmetaouts
-WHERE txid = $1
-AND block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+DELETE FROM mempool_vmetaouts
+WHERE txid = $1;
+
+
+-- name: GetSpaceDataHistory :many
+SELECT
+    vmetaouts.txid,
+    vmetaouts.block_hash,
+    blocks.height AS block_height,
+    vmetaouts.data,
+    vmetaouts.data_decoded,
+    vmetaouts.data_format
+FROM vmetaouts
+    INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+    INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+WHERE vmetaouts.name = $1
+AND vmetaouts.action = 'TRANSFER'
+AND NOT blocks.orphan
+ORDER BY blocks.height DESC, transactions.index DESC, vmetaouts.n DESC;
+
+
+-- name: GetSpaceBidHistory :many
+SELECT *
+FROM (
+    SELECT
+        vmetaouts.txid,
+        vmetaouts.block_hash,
+        vmetaouts.burn_increment,
+        vmetaouts.total_burned,
+        blocks.height AS block_height,
+        'confirmed'::text AS status,
+        NULL::bytea AS replaced_by,
+        transactions.index::bigint AS position
+    FROM vmetaouts
+        INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+        INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+    WHERE vmetaouts.name = $1
+    AND vmetaouts.action = 'BID'
+    AND NOT blocks.orphan
+    UNION ALL
+    SELECT
+        mempool_vmetaouts.txid,
+        NULL::bytea AS block_hash,
+        mempool_vmetaouts.burn_increment,
+        mempool_vmetaouts.total_burned,
+        -1 AS block_height,
+        (CASE
+            WHEN mempool_transactions.replaced_by IS NOT NULL THEN 'replaced'
+            ELSE 'pending'
+        END)::text AS status,
+        mempool_transactions.replaced_by,
+        mempool_transactions.seq AS position
+    FROM mempool_vmetaouts
+        INNER JOIN mempool_transactions ON (mempool_vmetaouts.txid = mempool_transactions.txid)
+    WHERE mempool_vmetaouts.name = $1
+    AND mempool_vmetaouts.action = 'BID'
+) AS bids
+ORDER BY block_height = -1 DESC, block_height DESC, position DESC;
+
+-- name: GetMempoolSpaceActions :many
+SELECT mempool_vmetaouts.*, mempool_transactions.seq
+FROM mempool_vmetaouts
+    INNER JOIN mempool_transactions ON (mempool_vmetaouts.txid = mempool_transactions.txid)
+WHERE mempool_transactions.replaced_by IS NULL
+AND mempool_vmetaouts.name IS NOT NULL
+ORDER BY mempool_vmetaouts.name, mempool_transactions.seq, mempool_vmetaouts.identifier;
+
+-- name: GetMempoolSpaceActionsByNames :many
+SELECT mempool_vmetaouts.*, mempool_transactions.seq
+FROM mempool_vmetaouts
+    INNER JOIN mempool_transactions ON (mempool_vmetaouts.txid = mempool_transactions.txid)
+WHERE mempool_transactions.replaced_by IS NULL
+AND mempool_vmetaouts.name = ANY($1::text[])
+ORDER BY mempool_vmetaouts.name, mempool_transactions.seq, mempool_vmetaouts.identifier;
+
+-- name: GetLatestSpaceStates :many
+SELECT
+    states.block_hash,
+    states.txid,
+    states.identifier,
+    states.priority,
+    states.name,
+    states.reason,
+    states.value,
+    states.scriptpubkey,
+    states.action,
+    states.burn_increment,
+    states.signature,
+    states.total_burned,
+    states.claim_height,
+    states.expire_height,
+    states.script_error,
+    states.outpoint_txid,
+    states.outpoint_index,
+    states.kind,
+    states.n,
+    states.raw_action,
+    states.script_error_type,
+    latest_transfer.data,
+    latest_transfer.data_decoded,
+    latest_transfer.data_format,
+    states.block_height
+FROM (
+    SELECT DISTINCT ON (vmetaouts.name) vmetaouts.*, blocks.height AS block_height
+    FROM vmetaouts
+        INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+        INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+    WHERE vmetaouts.name = ANY($1::text[])
+    AND NOT blocks.orphan
+    AND blocks.height >= 0
+    AND vmetaouts.action IS NOT NULL
+    AND vmetaouts.action <> 'REJECT'
+    ORDER BY vmetaouts.name, blocks.height DESC, transactions.index DESC, vmetaouts.identifier DESC
+) AS states
+    -- only a transfer sets the data of a space, as in GetSpaceDataHistory
+    LEFT JOIN LATERAL (
+        SELECT vmetaouts.data, vmetaouts.data_decoded, vmetaouts.data_format
+        FROM vmetaouts
+            INNER JOIN transactions ON (vmetaouts.block_hash = transactions.block_hash AND vmetaouts.txid = transactions.txid)
+            INNER JOIN blocks ON (vmetaouts.block_hash = blocks.hash)
+        WHERE vmetaouts.name = states.name
+        AND vmetaouts.action = 'TRANSFER'
+        AND NOT blocks.orphan
+        ORDER BY blocks.height DESC, transactions.index DESC, vmetaouts.n DESC
+        LIMIT 1
+    ) AS latest_transfer ON true
+ORDER BY states.name;
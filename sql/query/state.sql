@@ -0,0 +1,27 @@
+-- name: GetIndexerState :one
+SELECT *
+FROM indexer_state
+WHERE component = $1;
+
+-- name: GetIndexerStates :many
+SELECT *
+FROM indexer_state
+ORDER BY component;
+
+-- name: UpsertIndexerState :exec
+INSERT INTO indexer_state (
+    component,
+    height,
+    block_hash,
+    target_height,
+    schema_version,
+    indexer_version
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (component) DO UPDATE SET
+    height = EXCLUDED.height,
+    block_hash = EXCLUDED.block_hash,
+    target_height = COALESCE(EXCLUDED.target_height, indexer_state.target_height),
+    schema_version = EXCLUDED.schema_version,
+    indexer_version = EXCLUDED.indexer_version,
+    updated_at = now();
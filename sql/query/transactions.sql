@@ -4,12 +4,6 @@ INSERT INTO transactions (
     input_count, output_count, total_output_value
 ) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);
 
--- name: InsertMempoolTransaction :exec
-INSERT INTO transactions (
-    txid, tx_hash, version, size, vsize, weight, locktime, fee, block_hash,
-    input_count, output_count, total_output_value
-) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
-
 -- name: InsertBatchTransactions :copyfrom
 INSERT INTO transactions (
     txid, tx_hash, version, size, vsize, weight, locktime, fee, block_hash, index,
@@ -34,31 +28,3 @@ FROM
 WHERE blocks.height = $1
 ORDER BY transactions.index
 LIMIT $2 OFFSET $3;
-
-
--- name: GetMempoolTxids :many
-SELECT txid
-FROM transactions
-WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'
-ORDER BY index;
-
--- name: GetMempoolTransactions :many
-SELECT *
-FROM transactions
-WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'
-ORDER BY index
-LIMIT $1 OFFSET $2;
-
--- name: DeleteMempoolTransactions :exec
-DELETE FROM transactions
-WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef' and index <0;
-
--- name: DeleteMempoolTransactionByTxid :exec
-DELETE FROM transactions
-where txid = $1
-AND block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
-
--- name: DeleteMempoolTransactionsByTxids :exec
-DELETE FROM transactions
-WHERE txid = ANY($1::bytea[])
-AND block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
@@ -0,0 +1,27 @@
+-- name: GetBlocksInRange :many
+SELECT height, hash, root_anchor
+FROM blocks
+WHERE NOT orphan
+AND height BETWEEN @from_height::integer AND @to_height::integer
+ORDER BY height, hash;
+
+-- name: GetTxidsByBlockHash :many
+SELECT txid
+FROM transactions
+WHERE block_hash = $1
+ORDER BY index;
+
+-- name: GetVMetaOutKeysByBlockHash :many
+SELECT txid, name, action
+FROM vmetaouts
+WHERE block_hash = $1
+ORDER BY identifier;
+
+-- name: DeleteBlockByHash :exec
+DELETE FROM blocks
+WHERE hash = $1;
+
+-- name: OrphanBlocksAtHeight :exec
+UPDATE blocks SET orphan = true, height = -2
+WHERE height = @height::integer
+AND hash <> @hash;
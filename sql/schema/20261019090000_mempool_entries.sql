@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mempool_entries (
+    txid bytea PRIMARY KEY CHECK (LENGTH(txid) = 32),
+    first_seen bigint NOT NULL, -- unix time the node first saw the tx
+    vsize bigint NOT NULL,
+    weight bigint NOT NULL,
+    base_fee bigint NOT NULL,
+    modified_fee bigint NOT NULL,
+    fee_rate double precision NOT NULL, -- modified fee in sat/vB
+    ancestor_count integer NOT NULL,
+    ancestor_size bigint NOT NULL,
+    ancestor_fees bigint NOT NULL,
+    descendant_count integer NOT NULL,
+    descendant_size bigint NOT NULL,
+    descendant_fees bigint NOT NULL,
+    bip125_replaceable boolean NOT NULL DEFAULT FALSE,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX index_mempool_entries_fee_rate ON mempool_entries (fee_rate);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX index_mempool_entries_fee_rate;
+DROP TABLE mempool_entries;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions
+ADD COLUMN replaced_by bytea CHECK (replaced_by IS NULL OR LENGTH(replaced_by) = 32);
+
+-- outpoints spent by the mempool transactions, used to match conflicting inputs
+CREATE TABLE mempool_spends (
+    block_hash bytea NOT NULL,
+    txid bytea NOT NULL,
+    prev_txid bytea NOT NULL CHECK (LENGTH(prev_txid) = 32),
+    prev_index integer NOT NULL,
+
+    PRIMARY KEY (txid, prev_txid, prev_index),
+    FOREIGN KEY (block_hash, txid) REFERENCES transactions (block_hash, txid) ON DELETE CASCADE
+);
+
+CREATE INDEX index_mempool_spends_prevout ON mempool_spends (prev_txid, prev_index);
+
+CREATE TABLE mempool_replacements (
+    replaced_txid bytea NOT NULL CHECK (LENGTH(replaced_txid) = 32),
+    replacing_txid bytea NOT NULL CHECK (LENGTH(replacing_txid) = 32),
+    fee_delta bigint NOT NULL, -- replacing fee minus replaced fee, in sats
+    replaced_at timestamptz NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (replaced_txid, replacing_txid)
+);
+
+CREATE INDEX index_mempool_replacements_replacing_txid ON mempool_replacements (replacing_txid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX index_mempool_replacements_replacing_txid;
+DROP TABLE mempool_replacements;
+DROP INDEX index_mempool_spends_prevout;
+DROP TABLE mempool_spends;
+ALTER TABLE transactions DROP COLUMN IF EXISTS replaced_by;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE fee_snapshots (
+    id bigserial PRIMARY KEY,
+    taken_at timestamptz NOT NULL DEFAULT now(),
+    tx_count integer NOT NULL,
+    total_vsize bigint NOT NULL,
+    total_fees bigint NOT NULL
+);
+
+CREATE INDEX index_fee_snapshots_taken_at ON fee_snapshots (taken_at);
+
+CREATE TABLE fee_histogram_buckets (
+    snapshot_id bigint NOT NULL REFERENCES fee_snapshots (id) ON DELETE CASCADE,
+    fee_rate_min double precision NOT NULL, -- sat/vB, inclusive
+    fee_rate_max double precision, -- sat/vB, exclusive, null for the top bucket
+    tx_count integer NOT NULL,
+    total_vsize bigint NOT NULL,
+    total_fees bigint NOT NULL,
+
+    PRIMARY KEY (snapshot_id, fee_rate_min)
+);
+
+CREATE TABLE fee_estimates (
+    snapshot_id bigint NOT NULL REFERENCES fee_snapshots (id) ON DELETE CASCADE,
+    conf_target integer NOT NULL,
+    fee_rate double precision NOT NULL, -- sat/vB
+    blocks integer NOT NULL, -- the target the estimate was actually found for
+
+    PRIMARY KEY (snapshot_id, conf_target)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE fee_estimates;
+DROP TABLE fee_histogram_buckets;
+DROP INDEX index_fee_snapshots_taken_at;
+DROP TABLE fee_snapshots;
+-- +goose StatementEnd
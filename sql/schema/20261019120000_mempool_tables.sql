@@ -0,0 +1,156 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mempool_transactions (
+    txid bytea PRIMARY KEY CHECK (LENGTH(txid) = 32),
+    tx_hash bytea NOT NULL CHECK (LENGTH(tx_hash) = 32),
+    "version" integer NOT NULL,
+    "size" bigint NOT NULL,
+    vsize bigint NOT NULL,
+    weight bigint NOT NULL,
+    locktime integer NOT NULL,
+    fee bigint NOT NULL,
+    seq bigserial NOT NULL, -- order in which the indexer picked the tx up
+
+    input_count integer NOT NULL DEFAULT 0,
+    output_count integer NOT NULL DEFAULT 0,
+    total_output_value bigint NOT NULL DEFAULT 0,
+
+    replaced_by bytea CHECK (replaced_by IS NULL OR LENGTH(replaced_by) = 32),
+    added_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX index_mempool_transactions_seq ON mempool_transactions (seq);
+
+CREATE TABLE mempool_vmetaouts (
+    txid bytea NOT NULL REFERENCES mempool_transactions (txid) ON DELETE CASCADE,
+
+    identifier bigint PRIMARY KEY DEFAULT nextval('vmetaouts_identifier_seq'),
+
+    priority bigint,
+    name TEXT CHECK (LENGTH(name) < 64),
+    reason TEXT,
+    value bigint,
+    scriptPubKey bytea,
+
+    action covenant_action,
+    burn_increment bigint,
+    signature bytea,
+    total_burned bigint,
+
+    claim_height bigint,
+    expire_height bigint,
+
+    script_error text,
+
+    outpoint_txid bytea,
+    outpoint_index bigint
+);
+
+CREATE INDEX index_mempool_vmetaouts_name ON mempool_vmetaouts(name) WHERE name IS NOT NULL;
+CREATE INDEX index_mempool_vmetaouts_txid ON mempool_vmetaouts(txid);
+
+-- moving the rows of the sentinel mempool block, its negative tx index becomes the sequence
+INSERT INTO mempool_transactions (
+    txid, tx_hash, version, size, vsize, weight, locktime, fee, seq,
+    input_count, output_count, total_output_value, replaced_by
+)
+SELECT
+    txid, tx_hash, version, size, vsize, weight, locktime, fee, -index,
+    input_count, output_count, total_output_value, replaced_by
+FROM transactions
+WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+
+SELECT setval(pg_get_serial_sequence('mempool_transactions', 'seq'), COALESCE(MAX(seq), 0) + 1, false)
+FROM mempool_transactions;
+
+INSERT INTO mempool_vmetaouts (
+    txid, identifier, priority, name, reason, value, scriptPubKey, action, burn_increment,
+    signature, total_burned, claim_height, expire_height, script_error, outpoint_txid, outpoint_index
+)
+SELECT
+    txid, identifier, priority, name, reason, value, scriptPubKey, action, burn_increment,
+    signature, total_burned, claim_height, expire_height, script_error, outpoint_txid, outpoint_index
+FROM vmetaouts
+WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+
+ALTER TABLE mempool_spends DROP CONSTRAINT mempool_spends_block_hash_txid_fkey;
+ALTER TABLE mempool_spends DROP COLUMN block_hash;
+ALTER TABLE mempool_spends
+ADD FOREIGN KEY (txid) REFERENCES mempool_transactions (txid) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_transactions_mempool;
+DELETE FROM blocks WHERE hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+
+ALTER TABLE transactions ALTER COLUMN index DROP DEFAULT;
+DROP SEQUENCE IF EXISTS mempool_tx_index_seq;
+ALTER TABLE transactions DROP COLUMN IF EXISTS replaced_by;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions
+ADD COLUMN replaced_by bytea CHECK (replaced_by IS NULL OR LENGTH(replaced_by) = 32);
+
+CREATE SEQUENCE IF NOT EXISTS mempool_tx_index_seq
+    INCREMENT BY -1
+    MAXVALUE -1
+    NO MINVALUE
+    NO CYCLE;
+
+ALTER TABLE transactions
+    ALTER COLUMN index SET DEFAULT nextval('mempool_tx_index_seq');
+
+INSERT INTO blocks (
+    hash, size, stripped_size, weight, height, version, hash_merkle_root,
+    time, median_time, nonce, bits, difficulty, chainwork
+) VALUES (
+    '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'::bytea,
+    0, 0, 0, -1, 1,
+    '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'::bytea,
+    0, 0, 0,
+    '\xdeadbeef'::bytea,
+    0,
+    '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'::bytea
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_mempool
+ON transactions(block_hash)
+WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+
+INSERT INTO transactions (
+    txid, tx_hash, version, size, vsize, weight, locktime, fee, block_hash, index,
+    input_count, output_count, total_output_value, replaced_by
+)
+SELECT
+    txid, tx_hash, version, size, vsize, weight, locktime, fee,
+    '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'::bytea, -seq,
+    input_count, output_count, total_output_value, replaced_by
+FROM mempool_transactions;
+
+SELECT setval('mempool_tx_index_seq', COALESCE(MIN(index), 0) - 1, false)
+FROM transactions
+WHERE block_hash = '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef';
+
+INSERT INTO vmetaouts (
+    block_hash, txid, identifier, priority, name, reason, value, scriptPubKey, action, burn_increment,
+    signature, total_burned, claim_height, expire_height, script_error, outpoint_txid, outpoint_index
+)
+SELECT
+    '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'::bytea,
+    txid, identifier, priority, name, reason, value, scriptPubKey, action, burn_increment,
+    signature, total_burned, claim_height, expire_height, script_error, outpoint_txid, outpoint_index
+FROM mempool_vmetaouts;
+
+ALTER TABLE mempool_spends DROP CONSTRAINT mempool_spends_txid_fkey;
+ALTER TABLE mempool_spends
+ADD COLUMN block_hash bytea NOT NULL DEFAULT '\xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef'::bytea;
+ALTER TABLE mempool_spends ALTER COLUMN block_hash DROP DEFAULT;
+ALTER TABLE mempool_spends
+ADD FOREIGN KEY (block_hash, txid) REFERENCES transactions (block_hash, txid) ON DELETE CASCADE;
+
+DROP INDEX index_mempool_vmetaouts_txid;
+DROP INDEX index_mempool_vmetaouts_name;
+DROP TABLE mempool_vmetaouts;
+DROP INDEX index_mempool_transactions_seq;
+DROP TABLE mempool_transactions;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE indexer_state (
+    component text PRIMARY KEY, -- blocks, spaces, mempool, rollouts, root_anchors, backfill, populate
+    height integer NOT NULL, -- last processed height, -1 if nothing was processed
+    block_hash bytea,
+    target_height integer, -- end of the range a batch component is working towards
+    schema_version bigint NOT NULL,
+    indexer_version text NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO indexer_state (component, height, block_hash, schema_version, indexer_version)
+SELECT component, blocks.height, blocks.hash, 20261019130000, 'migration'
+FROM blocks, (VALUES ('blocks'), ('spaces')) AS components (component)
+WHERE blocks.height = (SELECT MAX(height) FROM blocks WHERE NOT orphan);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE indexer_state;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+-- creates and updates both describe the output n of the tx, a rejected spend its input n
+CREATE TYPE vmetaout_kind AS ENUM ('OUTPUT', 'SPEND');
+
+ALTER TABLE vmetaouts
+ADD COLUMN kind vmetaout_kind,
+ADD COLUMN n integer;
+
+UPDATE vmetaouts SET kind = 'OUTPUT', n = outpoint_index WHERE outpoint_index IS NOT NULL;
+UPDATE vmetaouts SET kind = 'SPEND' WHERE outpoint_index IS NULL;
+
+-- rows stored twice by a retried or repeated block keep their first copy
+DELETE FROM vmetaouts a
+USING vmetaouts b
+WHERE a.identifier > b.identifier
+AND a.block_hash = b.block_hash
+AND a.txid = b.txid
+AND a.kind = b.kind
+AND (
+    a.kind = 'OUTPUT' AND a.n = b.n
+    OR a.kind = 'SPEND'
+    AND a.name IS NOT DISTINCT FROM b.name
+    AND a.action IS NOT DISTINCT FROM b.action
+    AND a.script_error IS NOT DISTINCT FROM b.script_error
+);
+
+-- the input index of the spends was not stored, they are numbered in insertion order
+UPDATE vmetaouts SET n = spends.n
+FROM (
+    SELECT identifier, (ROW_NUMBER() OVER (PARTITION BY block_hash, txid ORDER BY identifier) - 1)::integer AS n
+    FROM vmetaouts
+    WHERE kind = 'SPEND'
+) AS spends
+WHERE vmetaouts.identifier = spends.identifier;
+
+ALTER TABLE vmetaouts
+ALTER COLUMN kind SET NOT NULL,
+ALTER COLUMN n SET NOT NULL,
+ADD CONSTRAINT vmetaouts_natural_key UNIQUE (block_hash, txid, kind, n);
+
+-- covered by the natural key
+DROP INDEX index_vmetaouts_blockhash_txid;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE INDEX index_vmetaouts_blockhash_txid ON vmetaouts(block_hash, txid);
+ALTER TABLE vmetaouts DROP CONSTRAINT vmetaouts_natural_key;
+ALTER TABLE vmetaouts DROP COLUMN n;
+ALTER TABLE vmetaouts DROP COLUMN kind;
+DROP TYPE vmetaout_kind;
+-- +goose StatementEnd
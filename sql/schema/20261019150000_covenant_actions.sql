@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE covenant_action ADD VALUE IF NOT EXISTS 'OPEN';
+ALTER TYPE covenant_action ADD VALUE IF NOT EXISTS 'REGISTER';
+-- a type spaced reported that the indexer does not know, its name is kept in raw_action
+ALTER TYPE covenant_action ADD VALUE IF NOT EXISTS 'UNKNOWN';
+
+ALTER TABLE vmetaouts ADD COLUMN raw_action text;
+ALTER TABLE mempool_vmetaouts ADD COLUMN raw_action text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- enum values can't be dropped, the rows using them keep them
+ALTER TABLE mempool_vmetaouts DROP COLUMN raw_action;
+ALTER TABLE vmetaouts DROP COLUMN raw_action;
+-- +goose StatementEnd
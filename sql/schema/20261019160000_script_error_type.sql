@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the error type spaced reported for a rejected spend, script_error keeps the reason and n the input index.
+-- rows stored before it had the type appended to the reason, storing their blocks again fills it
+ALTER TABLE vmetaouts ADD COLUMN script_error_type text;
+ALTER TABLE mempool_vmetaouts ADD COLUMN script_error_type text;
+
+CREATE INDEX index_vmetaouts_script_error_type ON vmetaouts(script_error_type) WHERE script_error_type IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX index_vmetaouts_script_error_type;
+ALTER TABLE mempool_vmetaouts DROP COLUMN script_error_type;
+ALTER TABLE vmetaouts DROP COLUMN script_error_type;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the data a covenant attaches to a space, data_decoded holds it as text when data_format is json or text
+ALTER TABLE vmetaouts
+ADD COLUMN data bytea,
+ADD COLUMN data_decoded text,
+ADD COLUMN data_format text;
+
+ALTER TABLE mempool_vmetaouts
+ADD COLUMN data bytea,
+ADD COLUMN data_decoded text,
+ADD COLUMN data_format text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mempool_vmetaouts
+DROP COLUMN data_format,
+DROP COLUMN data_decoded,
+DROP COLUMN data;
+
+ALTER TABLE vmetaouts
+DROP COLUMN data_format,
+DROP COLUMN data_decoded,
+DROP COLUMN data;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX index_mempool_transactions_replaced_by ON mempool_transactions(replaced_by)
+WHERE replaced_by IS NOT NULL;
+
+-- replaced txs whose replacing tx left the mempool before they were cleaned up, along with the txs they replaced
+WITH RECURSIVE gone AS (
+    SELECT m.txid
+    FROM mempool_transactions m
+    WHERE m.replaced_by IS NOT NULL
+      AND NOT EXISTS (SELECT 1 FROM mempool_transactions r WHERE r.txid = m.replaced_by)
+    UNION
+    SELECT m.txid
+    FROM mempool_transactions m
+    JOIN gone ON m.replaced_by = gone.txid
+)
+DELETE FROM mempool_transactions
+WHERE txid IN (SELECT txid FROM gone);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX index_mempool_transactions_replaced_by;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the mempool rows describe their output or input like the confirmed ones
+ALTER TABLE mempool_vmetaouts
+ADD COLUMN kind vmetaout_kind,
+ADD COLUMN n integer;
+
+UPDATE mempool_vmetaouts SET kind = 'OUTPUT', n = outpoint_index WHERE outpoint_index IS NOT NULL;
+UPDATE mempool_vmetaouts SET kind = 'SPEND' WHERE outpoint_index IS NULL;
+
+ALTER TABLE mempool_vmetaouts ALTER COLUMN kind SET NOT NULL;
+
+-- rejected spends stored before script_error_type have an unknown input index, and the type of
+-- anything but a reject appended to their script_error: they are flagged unparsed until stored again
+ALTER TABLE vmetaouts ADD COLUMN script_error_status text;
+ALTER TABLE mempool_vmetaouts ADD COLUMN script_error_status text;
+
+UPDATE vmetaouts SET script_error_status = 'unparsed'
+WHERE kind = 'SPEND' AND action = 'REJECT' AND script_error_type IS NULL;
+
+UPDATE mempool_vmetaouts SET script_error_status = 'unparsed'
+WHERE kind = 'SPEND' AND action = 'REJECT' AND script_error_type IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE vmetaouts DROP COLUMN script_error_status;
+
+ALTER TABLE mempool_vmetaouts
+DROP COLUMN script_error_status,
+DROP COLUMN n,
+DROP COLUMN kind;
+-- +goose StatementEnd
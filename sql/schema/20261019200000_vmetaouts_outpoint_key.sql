@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+-- an update describes an output of the tx it spends from, so outputs are keyed by their outpoint txid too
+ALTER TABLE vmetaouts DROP CONSTRAINT vmetaouts_natural_key;
+
+-- the natural key migration numbered the spends stored before script_error_type in insertion order,
+-- their input index is unknown until their block is stored again. Spends with an unknown n never
+-- conflict, the ones the natural key migration dropped as copies are restored by verify --repair.
+ALTER TABLE vmetaouts ALTER COLUMN n DROP NOT NULL;
+UPDATE vmetaouts SET n = NULL WHERE kind = 'SPEND' AND script_error_type IS NULL;
+
+CREATE UNIQUE INDEX vmetaouts_natural_key ON vmetaouts(block_hash, txid, kind, (COALESCE(outpoint_txid, ''::bytea)), n);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX vmetaouts_natural_key;
+
+-- the old key has no room for outputs of different outpoint txids at the same n, the first one is kept
+DELETE FROM vmetaouts a
+USING vmetaouts b
+WHERE a.identifier > b.identifier
+AND a.block_hash = b.block_hash
+AND a.txid = b.txid
+AND a.kind = 'OUTPUT'
+AND b.kind = 'OUTPUT'
+AND a.n = b.n;
+
+-- spends with an unknown input index are numbered after the known ones of their tx
+UPDATE vmetaouts SET n = spends.n
+FROM (
+    SELECT
+        identifier,
+        (COALESCE(MAX(n) OVER (PARTITION BY block_hash, txid), -1)
+            + ROW_NUMBER() OVER (PARTITION BY block_hash, txid, n IS NULL ORDER BY identifier))::integer AS n,
+        n IS NULL AS unknown
+    FROM vmetaouts
+    WHERE kind = 'SPEND'
+) AS spends
+WHERE vmetaouts.identifier = spends.identifier
+AND spends.unknown;
+
+ALTER TABLE vmetaouts
+ALTER COLUMN n SET NOT NULL,
+ADD CONSTRAINT vmetaouts_natural_key UNIQUE (block_hash, txid, kind, n);
+-- +goose StatementEnd